@@ -0,0 +1,150 @@
+package src
+
+import "sort"
+
+// Order of HDI categories in aggregate reports, highest development first
+var hdiCategories = []string{
+	"Very high human development",
+	"High human development",
+	"Medium human development",
+	"Low human development",
+}
+
+const (
+	notRanked     = "Not ranked"
+	unknownRegion = "Unknown region"
+)
+
+// Aggregate summarises a group of countries. The HDI figures are averages
+// weighted by population over the countries that have a value; HDICountries
+// and HDIPopulation say how much of the group they cover.
+type Aggregate struct {
+	Group             string  `json:"group"`
+	Countries         int     `json:"countries"`
+	Population        int     `json:"population"`
+	Area              float64 `json:"area"`
+	HDICountries      int     `json:"hdi_countries"`
+	HDIPopulation     int     `json:"hdi_population"`
+	HDIValue          float64 `json:"hdi_value"`
+	LifeExpect        float64 `json:"life_expectancy"`
+	ExpectedSchooling float64 `json:"expected_schooling"`
+	MeanSchooling     float64 `json:"mean_schooling"`
+	GNIPerCapita      float64 `json:"gni_per_capita"`
+}
+
+// AggregateReport groups countries by region and by HDI category.
+type AggregateReport struct {
+	World      Aggregate   `json:"world"`
+	Regions    []Aggregate `json:"regions"`
+	Categories []Aggregate `json:"categories"`
+}
+
+// AggregateTable is one summary table of the home page, see the
+// aggregateTable template.
+type AggregateTable struct {
+	Title string
+	Rows  []Aggregate
+}
+
+func newAggregateTable(title string, rows []Aggregate) AggregateTable {
+	return AggregateTable{Title: title, Rows: rows}
+}
+
+// weightedMean accumulates a population-weighted average, skipping missing
+// (zero) values.
+type weightedMean struct {
+	sum, weight float64
+}
+
+func (m *weightedMean) add(value float64, population int) {
+	if value == 0 || population <= 0 {
+		return
+	}
+	m.sum += value * float64(population)
+	m.weight += float64(population)
+}
+
+func (m weightedMean) value() float64 {
+	if m.weight == 0 {
+		return 0
+	}
+	return m.sum / m.weight
+}
+
+type aggregator struct {
+	Aggregate
+	hdi, life, expected, mean, gni weightedMean
+}
+
+func (a *aggregator) add(country Country) {
+	a.Countries++
+	a.Population += country.Population
+	a.Area += country.Area
+	if country.HDI.HDIValue != 0 {
+		a.HDICountries++
+		a.HDIPopulation += country.Population
+	}
+	a.hdi.add(country.HDI.HDIValue, country.Population)
+	a.life.add(country.HDI.LifeExpect, country.Population)
+	a.expected.add(country.HDI.ExpectedSchooling, country.Population)
+	a.mean.add(country.HDI.MeanSchooling, country.Population)
+	a.gni.add(country.HDI.GNIPerCapita, country.Population)
+}
+
+func (a *aggregator) result() Aggregate {
+	result := a.Aggregate
+	result.HDIValue = a.hdi.value()
+	result.LifeExpect = a.life.value()
+	result.ExpectedSchooling = a.expected.value()
+	result.MeanSchooling = a.mean.value()
+	result.GNIPerCapita = a.gni.value()
+	return result
+}
+
+// buildAggregates computes the world total and the per-region and
+// per-category aggregates. Regions are sorted by name; categories follow
+// hdiCategories, with countries lacking HDI data in a final "Not ranked"
+// group.
+func buildAggregates(countries []Country) AggregateReport {
+	world := &aggregator{Aggregate: Aggregate{Group: "World"}}
+	regions := make(map[string]*aggregator)
+	categories := make(map[string]*aggregator)
+
+	group := func(groups map[string]*aggregator, name string) *aggregator {
+		if groups[name] == nil {
+			groups[name] = &aggregator{Aggregate: Aggregate{Group: name}}
+		}
+		return groups[name]
+	}
+
+	for _, country := range countries {
+		world.add(country)
+		region := country.Region
+		if region == "" {
+			region = unknownRegion
+		}
+		group(regions, region).add(country)
+
+		category := country.HDI.Category
+		if category == "" {
+			category = notRanked
+		}
+		group(categories, category).add(country)
+	}
+
+	report := AggregateReport{World: world.result()}
+	for _, region := range regions {
+		report.Regions = append(report.Regions, region.result())
+	}
+	sort.Slice(report.Regions, func(i, j int) bool { return report.Regions[i].Group < report.Regions[j].Group })
+
+	for _, name := range hdiCategories {
+		if category, ok := categories[name]; ok {
+			report.Categories = append(report.Categories, category.result())
+		}
+	}
+	if category, ok := categories[notRanked]; ok {
+		report.Categories = append(report.Categories, category.result())
+	}
+	return report
+}
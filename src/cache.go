@@ -0,0 +1,84 @@
+package src
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// httpCacheEntry is the metadata stored next to a cached response body.
+type httpCacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	ValidatedAt  time.Time `json:"validated_at"`
+}
+
+// httpCache stores upstream responses on disk, one body and one metadata
+// file per URL.
+type httpCache struct {
+	dir string
+}
+
+func newHTTPCache(dir string) *httpCache {
+	return &httpCache{dir: dir}
+}
+
+func (c *httpCache) paths(url string) (string, string) {
+	sum := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(sum[:8])
+	return filepath.Join(c.dir, key+".body"), filepath.Join(c.dir, key+".json")
+}
+
+func (c *httpCache) load(url string) (httpCacheEntry, []byte, error) {
+	var entry httpCacheEntry
+	bodyPath, metaPath := c.paths(url)
+
+	meta, err := os.ReadFile(metaPath)
+	if err != nil {
+		return entry, nil, err
+	}
+	if err := json.Unmarshal(meta, &entry); err != nil {
+		return entry, nil, err
+	}
+
+	body, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return entry, nil, err
+	}
+	return entry, body, nil
+}
+
+func (c *httpCache) store(url string, entry httpCacheEntry, body []byte) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	bodyPath, _ := c.paths(url)
+	if err := writeFileAtomic(bodyPath, body); err != nil {
+		return err
+	}
+	return c.storeEntry(url, entry)
+}
+
+func (c *httpCache) storeEntry(url string, entry httpCacheEntry) error {
+	_, metaPath := c.paths(url)
+	meta, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(metaPath, meta)
+}
+
+// writeFileAtomic writes through a temporary file so that readers never see
+// a partially written cache entry.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
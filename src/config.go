@@ -1,9 +1,33 @@
 package src
 
+import "time"
+
 const (
     itemsPerPage         = 12
     favoritesFile        = "favorites.json"
+    hdiAliasesFile       = "hdi-aliases.json"
     timezonesGeojsonPath = "data"
+
+    countriesAPIURL       = "https://restcountries.com/v3.1/all?fields=name,cca2,cca3,ccn3,capital,capitalInfo,region,flag,timezones,population,area,languages,currencies,idd,car,borders"
+    countriesSnapshotFile = "countries-snapshot.json"
+    snapshotVersion       = 1
+    defaultCacheDir       = ".cache"
+    defaultHDIDir         = "hdi"
+    defaultIndicatorsDir  = "indicators"
+    defaultZoneInfoDir    = "/usr/share/zoneinfo"
+
+    upstreamTimeout  = 15 * time.Second
+    upstreamAttempts = 3
+    upstreamBackoff  = 2 * time.Second
+
+    // Delay between load attempts while the server runs without data
+    degradedRetryDelay    = 30 * time.Second
+    maxDegradedRetryDelay = 10 * time.Minute
+
+    // Values accepted by the COUNTRIES_SOURCE environment variable
+    sourceAPI      = "api"
+    sourceSnapshot = "snapshot"
+    sourceAuto     = "auto"
 )
 
 var (
@@ -0,0 +1,203 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Status of the source time of a conversion
+const (
+	localTimeValid       = "valid"
+	localTimeAmbiguous   = "ambiguous"   // occurs twice, when clocks go back
+	localTimeNonexistent = "nonexistent" // skipped, when clocks go forward
+)
+
+const maxConversionTargets = 50
+
+// ConvertedTime is a local time in one place.
+type ConvertedTime struct {
+	Name   string    `json:"name"`
+	Zone   string    `json:"zone"`
+	Local  string    `json:"local"`
+	Offset UTCOffset `json:"offset"`
+	Date   string    `json:"date"`
+	Time   string    `json:"time"`
+
+	// Calendar days from the source date, and the same in words
+	DayShift int    `json:"dayShift"`
+	Day      string `json:"day"`
+}
+
+// Conversion is the answer of /api/convert. For an ambiguous source time,
+// Alternatives holds both instants it may mean; for a nonexistent one,
+// Requested is the wall-clock time asked for and From the time it was moved
+// to.
+type Conversion struct {
+	From         ConvertedTime   `json:"from"`
+	Instant      time.Time       `json:"instant"`
+	Status       string          `json:"status"`
+	Note         string          `json:"note,omitempty"`
+	Requested    string          `json:"requested,omitempty"`
+	Alternatives []time.Time     `json:"alternatives,omitempty"`
+	To           []ConvertedTime `json:"to"`
+}
+
+// convertTime answers a conversion query: time (a local date-time, see
+// localTimeLayouts), from (a country or zone, see resolvePlace), to (one or
+// more, repeated), fold (earlier or later, which occurrence an ambiguous time
+// means) and strict (reject ambiguous and nonexistent times instead of
+// resolving them).
+func convertTime(countries []Country, query url.Values) (Conversion, error) {
+	value := query.Get("time")
+	if value == "" {
+		return Conversion{}, errors.New("missing time")
+	}
+	var wall time.Time
+	var err error
+	for _, layout := range localTimeLayouts {
+		if wall, err = time.ParseInLocation(layout, value, time.UTC); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return Conversion{}, fmt.Errorf("invalid time %q, use 2006-01-02T15:04", value)
+	}
+
+	from, err := resolvePlace(countries, query.Get("from"))
+	if err != nil {
+		return Conversion{}, fmt.Errorf("from: %w", err)
+	}
+	targets := query["to"]
+	if len(targets) == 0 {
+		return Conversion{}, errors.New("at least one to is required")
+	}
+	if len(targets) > maxConversionTargets {
+		return Conversion{}, fmt.Errorf("at most %d targets", maxConversionTargets)
+	}
+
+	fold := query.Get("fold")
+	if fold != "" && fold != "earlier" && fold != "later" {
+		return Conversion{}, fmt.Errorf("invalid fold: %s, use earlier or later", fold)
+	}
+	strict := query.Get("strict") == "1" || query.Get("strict") == "true"
+
+	conversion := Conversion{Status: localTimeValid}
+	candidates := localInstants(wall, from.loc)
+	switch len(candidates) {
+	case 0:
+		if strict {
+			return Conversion{}, fmt.Errorf("%s does not exist in %s, the clocks skip it", wall.Format("2006-01-02 15:04"), from.zone)
+		}
+		// Like most calendars, move forward by the length of the gap
+		before := offsetAt(wall.Add(-12 * time.Hour).In(from.loc))
+		conversion.Instant = wall.Add(-time.Duration(before) * time.Minute)
+		conversion.Status = localTimeNonexistent
+		conversion.Requested = wall.Format("2006-01-02T15:04:05")
+		moved := conversion.Instant.In(from.loc)
+		if calendarDays(wall, moved) == 0 {
+			conversion.Note = fmt.Sprintf("the clocks skip %s in %s, moved to %s",
+				wall.Format("15:04"), from.zone, moved.Format("15:04"))
+		} else {
+			// Whole days can be skipped, as in Samoa on 2011-12-30
+			conversion.Note = fmt.Sprintf("the clocks skip %s in %s, moved to %s on %s",
+				wall.Format("2006-01-02 15:04"), from.zone, moved.Format("15:04"), moved.Format("2006-01-02"))
+		}
+	case 1:
+		conversion.Instant = candidates[0]
+	default:
+		if strict {
+			return Conversion{}, fmt.Errorf("%s happens twice in %s, the clocks go back", wall.Format("2006-01-02 15:04"), from.zone)
+		}
+		occurrence := "earlier"
+		conversion.Instant = candidates[0]
+		if fold == "later" {
+			occurrence = "later"
+			conversion.Instant = candidates[len(candidates)-1]
+		}
+		conversion.Status = localTimeAmbiguous
+		conversion.Alternatives = candidates
+		conversion.Note = fmt.Sprintf("%s happens twice in %s, using the %s occurrence (set fold to earlier or later)",
+			wall.Format("15:04"), from.zone, occurrence)
+	}
+
+	// Day shifts count from the requested date, even if the time was moved
+	sourceDate := wall
+	conversion.From = newConvertedTime(from, conversion.Instant, sourceDate)
+	for _, key := range targets {
+		target, err := resolvePlace(countries, key)
+		if err != nil {
+			return Conversion{}, fmt.Errorf("to: %w", err)
+		}
+		conversion.To = append(conversion.To, newConvertedTime(target, conversion.Instant, sourceDate))
+	}
+	return conversion, nil
+}
+
+// localInstants returns the instants at which clocks in loc show the given
+// wall-clock time (read as UTC fields): none in a gap, two in an overlap.
+func localInstants(wall time.Time, loc *time.Location) []time.Time {
+	var offsets []UTCOffset
+	for _, shift := range []time.Duration{-36 * time.Hour, 0, 36 * time.Hour} {
+		offset := offsetAt(wall.Add(shift).In(loc))
+		if !slices.Contains(offsets, offset) {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	var instants []time.Time
+	for _, offset := range offsets {
+		instant := wall.Add(-time.Duration(offset) * time.Minute)
+		local := instant.In(loc)
+		if local.Format(time.DateTime) == wall.Format(time.DateTime) && !slices.ContainsFunc(instants, instant.Equal) {
+			instants = append(instants, instant)
+		}
+	}
+	slices.SortFunc(instants, func(a, b time.Time) int { return a.Compare(b) })
+	return instants
+}
+
+// newConvertedTime describes instant in p, with the shift in calendar days
+// from source, the source's local time.
+func newConvertedTime(p place, instant, source time.Time) ConvertedTime {
+	local := instant.In(p.loc)
+	converted := ConvertedTime{
+		Name:   p.name,
+		Zone:   p.zone,
+		Local:  local.Format("2006-01-02T15:04:05"),
+		Offset: offsetAt(local),
+		Date:   local.Format("2006-01-02"),
+		Time:   local.Format("15:04"),
+	}
+
+	converted.DayShift = calendarDays(source, local)
+	switch converted.DayShift {
+	case 0:
+		converted.Day = "same day"
+	case -1:
+		converted.Day = "previous day"
+	case 1:
+		converted.Day = "next day"
+	default:
+		converted.Day = fmt.Sprintf("%+d days", converted.DayShift)
+	}
+	return converted
+}
+
+// calendarDays returns the number of days from the calendar date of from to
+// that of to, each read in its own location.
+func calendarDays(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay).Hours() / 24)
+}
+
+// formatDayShift renders a shift in calendar days as "+1 day" or "-2 days".
+func formatDayShift(shift int) string {
+	if shift == 1 || shift == -1 {
+		return fmt.Sprintf("%+d day", shift)
+	}
+	return fmt.Sprintf("%+d days", shift)
+}
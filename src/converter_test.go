@@ -0,0 +1,177 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConvertTime(t *testing.T) {
+	type target struct {
+		zone     string
+		local    string
+		dayShift int
+	}
+	tests := []struct {
+		name         string
+		query        string
+		status       string
+		instant      string
+		from         target
+		alternatives int
+		note         string
+		to           []target
+	}{
+		{
+			name:    "valid",
+			query:   "time=2025-03-13T14:00&from=Europe/Paris&to=Asia/Kolkata",
+			status:  localTimeValid,
+			instant: "2025-03-13T13:00:00Z",
+			from:    target{"Europe/Paris", "2025-03-13T14:00:00", 0},
+			to:      []target{{"Asia/Kolkata", "2025-03-13T18:30:00", 0}},
+		},
+		{
+			name:         "ambiguous, earlier by default",
+			query:        "time=2025-10-26T02:30&from=Europe/Paris&to=UTC",
+			status:       localTimeAmbiguous,
+			instant:      "2025-10-26T00:30:00Z",
+			from:         target{"Europe/Paris", "2025-10-26T02:30:00", 0},
+			alternatives: 2,
+			note:         "earlier occurrence",
+			to:           []target{{"UTC", "2025-10-26T00:30:00", 0}},
+		},
+		{
+			name:         "ambiguous, later",
+			query:        "time=2025-10-26T02:30&from=Europe/Paris&to=UTC&fold=later",
+			status:       localTimeAmbiguous,
+			instant:      "2025-10-26T01:30:00Z",
+			from:         target{"Europe/Paris", "2025-10-26T02:30:00", 0},
+			alternatives: 2,
+			note:         "later occurrence",
+			to:           []target{{"UTC", "2025-10-26T01:30:00", 0}},
+		},
+		{
+			name:    "nonexistent, moved forward",
+			query:   "time=2025-03-30T02:30&from=Europe/Paris&to=UTC",
+			status:  localTimeNonexistent,
+			instant: "2025-03-30T01:30:00Z",
+			from:    target{"Europe/Paris", "2025-03-30T03:30:00", 0},
+			note:    "moved to 03:30",
+			to:      []target{{"UTC", "2025-03-30T01:30:00", 0}},
+		},
+		{
+			name:    "skipped day",
+			query:   "time=2011-12-30T10:00&from=Pacific/Apia&to=UTC",
+			status:  localTimeNonexistent,
+			instant: "2011-12-30T20:00:00Z",
+			from:    target{"Pacific/Apia", "2011-12-31T10:00:00", 1},
+			note:    "moved to 10:00 on 2011-12-31",
+			to:      []target{{"UTC", "2011-12-30T20:00:00", 0}},
+		},
+		{
+			name:    "day shifts across several targets",
+			query:   "time=2025-03-13T10:00&from=Pacific/Kiritimati&to=Pacific/Pago_Pago&to=UTC&to=Asia/Tokyo",
+			status:  localTimeValid,
+			instant: "2025-03-12T20:00:00Z",
+			from:    target{"Pacific/Kiritimati", "2025-03-13T10:00:00", 0},
+			to: []target{
+				{"Pacific/Pago_Pago", "2025-03-12T09:00:00", -1},
+				{"UTC", "2025-03-12T20:00:00", -1},
+				{"Asia/Tokyo", "2025-03-13T05:00:00", 0},
+			},
+		},
+		{
+			name:    "day shift the other way",
+			query:   "time=2025-03-12T09:00&from=Pacific/Pago_Pago&to=Pacific/Kiritimati",
+			status:  localTimeValid,
+			instant: "2025-03-12T20:00:00Z",
+			from:    target{"Pacific/Pago_Pago", "2025-03-12T09:00:00", 0},
+			to:      []target{{"Pacific/Kiritimati", "2025-03-13T10:00:00", 1}},
+		},
+	}
+	check := func(t *testing.T, got ConvertedTime, want target) {
+		t.Helper()
+		if got.Zone != want.zone || got.Local != want.local || got.DayShift != want.dayShift {
+			t.Errorf("got %s %s (%+d), want %s %s (%+d)", got.Zone, got.Local, got.DayShift, want.zone, want.local, want.dayShift)
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conversion, err := convertTime(nil, query)
+			if err != nil {
+				t.Fatalf("convertTime() error = %v", err)
+			}
+			if conversion.Status != tt.status {
+				t.Errorf("status = %s, want %s", conversion.Status, tt.status)
+			}
+			if got := conversion.Instant.UTC().Format(time.RFC3339); got != tt.instant {
+				t.Errorf("instant = %s, want %s", got, tt.instant)
+			}
+			if len(conversion.Alternatives) != tt.alternatives {
+				t.Errorf("got %d alternatives, want %d", len(conversion.Alternatives), tt.alternatives)
+			}
+			if !strings.Contains(conversion.Note, tt.note) {
+				t.Errorf("note = %q, want it to mention %q", conversion.Note, tt.note)
+			}
+			check(t, conversion.From, tt.from)
+			if len(conversion.To) != len(tt.to) {
+				t.Fatalf("got %d targets, want %d", len(conversion.To), len(tt.to))
+			}
+			for i := range tt.to {
+				check(t, conversion.To[i], tt.to[i])
+			}
+		})
+	}
+}
+
+func TestHandleConvertAPIStrict(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"valid", "time=2025-03-13T14:00&from=Europe/Paris&to=UTC&strict=1", http.StatusOK},
+		{"ambiguous", "time=2025-10-26T02:30&from=Europe/Paris&to=UTC&strict=1", http.StatusBadRequest},
+		{"nonexistent", "time=2025-03-30T02:30&from=Europe/Paris&to=UTC&strict=true", http.StatusBadRequest},
+		{"nonexistent, not strict", "time=2025-03-30T02:30&from=Europe/Paris&to=UTC", http.StatusOK},
+		{"bad fold", "time=2025-10-26T02:30&from=Europe/Paris&to=UTC&fold=both", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handleConvertAPI(recorder, httptest.NewRequest(http.MethodGet, "/api/convert?"+tt.query, nil))
+			if recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d, body %s", recorder.Code, tt.want, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestLocalInstants(t *testing.T) {
+	paris, err := loadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		wall string
+		want int
+	}{
+		{"2025-03-13T14:00:00", 1},
+		{"2025-03-30T02:30:00", 0},
+		{"2025-10-26T02:30:00", 2},
+		{"2025-10-26T03:00:00", 1},
+	}
+	for _, tt := range tests {
+		wall, _ := time.Parse("2006-01-02T15:04:05", tt.wall)
+		if got := localInstants(wall, paris); len(got) != tt.want {
+			t.Errorf("localInstants(%s) = %v, want %d instants", tt.wall, got, tt.want)
+		}
+	}
+}
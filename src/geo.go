@@ -0,0 +1,196 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// tzPolygon is one polygon of a time zone boundary from the GeoJSON files in
+// data/. Coordinates are [longitude, latitude]; the first ring is the outer
+// boundary and the others are holes.
+type tzPolygon struct {
+	tzid                           string
+	rings                          [][][2]float64
+	minLng, minLat, maxLng, maxLat float64
+}
+
+type tzFeature struct {
+	Properties struct {
+		Tzid string `json:"tzid"`
+	} `json:"properties"`
+	Geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+}
+
+var (
+	timeZonePolygonsOnce sync.Once
+	timeZonePolygonsData []tzPolygon
+)
+
+// timeZonePolygons returns the polygons of the boundary files in
+// timezonesGeojsonPath. They do not change while the server runs, so they are
+// parsed on first use only.
+func timeZonePolygons() []tzPolygon {
+	timeZonePolygonsOnce.Do(func() {
+		var err error
+		if timeZonePolygonsData, err = loadTimeZonePolygons(timezonesGeojsonPath); err != nil {
+			log.Printf("Warning: Could not load the time zone boundaries: %v", err)
+		}
+	})
+	return timeZonePolygonsData
+}
+
+// loadTimeZonePolygons reads the polygons of every *.geojson file in dir.
+// The files may cover only part of the world; points outside them simply
+// have no zone.
+func loadTimeZonePolygons(dir string) ([]tzPolygon, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.geojson"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var polygons []tzPolygon
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var collection struct {
+			Features []tzFeature `json:"features"`
+		}
+		if err := json.Unmarshal(content, &collection); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, feature := range collection.Features {
+			parsed, err := feature.polygons()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, feature.Properties.Tzid, err)
+			}
+			polygons = append(polygons, parsed...)
+		}
+	}
+	return polygons, nil
+}
+
+func (f tzFeature) polygons() ([]tzPolygon, error) {
+	var rings [][][][2]float64
+	switch f.Geometry.Type {
+	case "Polygon":
+		var polygon [][][2]float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &polygon); err != nil {
+			return nil, err
+		}
+		rings = append(rings, polygon)
+	case "MultiPolygon":
+		if err := json.Unmarshal(f.Geometry.Coordinates, &rings); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geometry %q", f.Geometry.Type)
+	}
+
+	polygons := make([]tzPolygon, 0, len(rings))
+	for _, polygon := range rings {
+		if len(polygon) == 0 {
+			continue
+		}
+		p := tzPolygon{tzid: f.Properties.Tzid, rings: polygon,
+			minLng: math.Inf(1), minLat: math.Inf(1), maxLng: math.Inf(-1), maxLat: math.Inf(-1)}
+		for _, point := range polygon[0] {
+			p.minLng, p.maxLng = math.Min(p.minLng, point[0]), math.Max(p.maxLng, point[0])
+			p.minLat, p.maxLat = math.Min(p.minLat, point[1]), math.Max(p.maxLat, point[1])
+		}
+		polygons = append(polygons, p)
+	}
+	return polygons, nil
+}
+
+// contains reports whether the point lies inside the outer ring and outside
+// every hole.
+func (p tzPolygon) contains(lat, lng float64) bool {
+	if lng < p.minLng || lng > p.maxLng || lat < p.minLat || lat > p.maxLat {
+		return false
+	}
+	if !ringContains(p.rings[0], lat, lng) {
+		return false
+	}
+	for _, hole := range p.rings[1:] {
+		if ringContains(hole, lat, lng) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains is the even-odd ray casting test.
+func ringContains(ring [][2]float64, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// zoneAt returns the tzid of the polygon containing the point, if any, among
+// the given zones only.
+func zoneAt(polygons []tzPolygon, lat, lng float64, zones []string) string {
+	for _, polygon := range polygons {
+		if slices.Contains(zones, polygon.tzid) && polygon.contains(lat, lng) {
+			return polygon.tzid
+		}
+	}
+	return ""
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// distanceKm is the great-circle distance between two points.
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371
+	dLat, dLng := radians(lat2-lat1), radians(lng2-lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// Elevation of the sun's centre at sunrise and sunset, allowing for
+// refraction and the size of the disc
+const sunriseElevation = -0.833
+
+// sunElevation returns the elevation of the sun in degrees above the horizon
+// at a point and instant, using NOAA's approximate solar position equations
+// (good to a fraction of a degree).
+func sunElevation(at time.Time, lat, lng float64) float64 {
+	t := at.UTC()
+	hours := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+	gamma := 2 * math.Pi / 365 * (float64(t.YearDay()-1) + (hours-12)/24)
+
+	equationOfTime := 229.18 * (0.000075 + 0.001868*math.Cos(gamma) - 0.032077*math.Sin(gamma) -
+		0.014615*math.Cos(2*gamma) - 0.040849*math.Sin(2*gamma))
+	declination := 0.006918 - 0.399912*math.Cos(gamma) + 0.070257*math.Sin(gamma) -
+		0.006758*math.Cos(2*gamma) + 0.000907*math.Sin(2*gamma) -
+		0.002697*math.Cos(3*gamma) + 0.00148*math.Sin(3*gamma)
+
+	solarMinutes := hours*60 + equationOfTime + 4*lng
+	hourAngle := radians(solarMinutes/4 - 180)
+	cosZenith := math.Sin(radians(lat))*math.Sin(declination) +
+		math.Cos(radians(lat))*math.Cos(declination)*math.Cos(hourAngle)
+	return 90 - math.Acos(math.Max(-1, math.Min(1, cosZenith)))*180/math.Pi
+}
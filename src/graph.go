@@ -0,0 +1,187 @@
+package src
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// neighbourGraph links countries that share a land border. Nodes are cca3
+// codes; every edge is stored in both directions.
+type neighbourGraph struct {
+	edges map[string][]string
+}
+
+func buildNeighbourGraph(countries []Country) *neighbourGraph {
+	known := make(map[string]bool, len(countries))
+	for _, country := range countries {
+		if country.Cca3 != "" {
+			known[country.Cca3] = true
+		}
+	}
+
+	sets := make(map[string]map[string]bool, len(known))
+	for code := range known {
+		sets[code] = make(map[string]bool)
+	}
+	for _, country := range countries {
+		for _, border := range country.Borders {
+			if !known[country.Cca3] || !known[border] || border == country.Cca3 {
+				continue
+			}
+			sets[country.Cca3][border] = true
+			sets[border][country.Cca3] = true
+		}
+	}
+
+	g := &neighbourGraph{edges: make(map[string][]string, len(sets))}
+	for code, set := range sets {
+		neighbours := make([]string, 0, len(set))
+		for neighbour := range set {
+			neighbours = append(neighbours, neighbour)
+		}
+		sort.Strings(neighbours)
+		g.edges[code] = neighbours
+	}
+	return g
+}
+
+func (g *neighbourGraph) has(code string) bool {
+	_, ok := g.edges[code]
+	return ok
+}
+
+func (g *neighbourGraph) neighbours(code string) []string {
+	return g.edges[code]
+}
+
+// shortestPath returns the countries crossed on the shortest land route from
+// one country to another, both included, or nil if there is none.
+func (g *neighbourGraph) shortestPath(from, to string) []string {
+	if !g.has(from) || !g.has(to) {
+		return nil
+	}
+	if from == to {
+		return []string{from}
+	}
+
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range g.edges[current] {
+			if _, seen := previous[next]; seen {
+				continue
+			}
+			previous[next] = current
+			if next == to {
+				path := []string{to}
+				for step := current; step != ""; step = previous[step] {
+					path = append([]string{step}, path...)
+				}
+				return path
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
+
+// components groups countries into land masses connected by borders,
+// largest first. Countries without land borders form their own component.
+func (g *neighbourGraph) components() [][]string {
+	codes := make([]string, 0, len(g.edges))
+	for code := range g.edges {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	seen := make(map[string]bool, len(codes))
+	var components [][]string
+	for _, start := range codes {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		component := []string{start}
+		for i := 0; i < len(component); i++ {
+			for _, next := range g.edges[component[i]] {
+				if !seen[next] {
+					seen[next] = true
+					component = append(component, next)
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool { return len(components[i]) > len(components[j]) })
+	return components
+}
+
+// resolveNeighbours fills in Country.Neighbours from the raw border codes.
+// Codes that do not match any loaded country are left out. Time differences
+// depend on the instant and are set per request by withLocalTimes.
+func resolveNeighbours(countries []Country) {
+	byCode := make(map[string]int, len(countries))
+	for i, country := range countries {
+		if country.Cca3 != "" {
+			byCode[country.Cca3] = i
+		}
+	}
+
+	for i := range countries {
+		neighbours := make([]Neighbour, 0, len(countries[i].Borders))
+		for _, code := range countries[i].Borders {
+			j, ok := byCode[code]
+			if !ok {
+				continue
+			}
+			to := countries[j]
+			neighbours = append(neighbours, Neighbour{Code: to.Cca3, Name: to.Name, Flag: to.Flag, loc: countryLocation(to)})
+		}
+		sort.Slice(neighbours, func(a, b int) bool { return neighbours[a].Name < neighbours[b].Name })
+		countries[i].Neighbours = neighbours
+	}
+}
+
+// newNeighbour describes to as seen from from at the given instant.
+func newNeighbour(from, to Country, at time.Time) Neighbour {
+	neighbour := Neighbour{Code: to.Cca3, Name: to.Name, Flag: to.Flag, loc: countryLocation(to)}
+	return neighbour.withTimeDifference(countryLocation(from), at)
+}
+
+// withTimeDifference sets the difference between the neighbour's clocks and
+// those of from at the given instant, so daylight saving time on either side
+// of the border is taken into account.
+func (n Neighbour) withTimeDifference(from *time.Location, at time.Time) Neighbour {
+	difference := int(offsetAt(at.In(n.loc)) - offsetAt(at.In(from)))
+	n.TimeDifferenceMinutes = difference
+	n.TimeDifference = formatTimeDifference(difference)
+	return n
+}
+
+// neighboursAt returns a copy of the country's neighbours with their time
+// differences at the given instant.
+func neighboursAt(country Country, at time.Time) []Neighbour {
+	loc := countryLocation(country)
+	neighbours := make([]Neighbour, len(country.Neighbours))
+	for i, neighbour := range country.Neighbours {
+		neighbours[i] = neighbour.withTimeDifference(loc, at)
+	}
+	return neighbours
+}
+
+// findCountry looks a country up by cca3, cca2 or name, ignoring case.
+func findCountry(countries []Country, key string) (Country, bool) {
+	key = strings.TrimSpace(key)
+	for _, country := range countries {
+		if strings.EqualFold(country.Cca3, key) || strings.EqualFold(country.Cca2, key) ||
+			strings.EqualFold(country.Name, key) {
+			return country, true
+		}
+	}
+	return Country{}, false
+}
@@ -0,0 +1,95 @@
+package src
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testGraph is a small map: a mainland chain from Portugal to Poland, a
+// two-country island, two single-country islands and a border with a
+// country that is not loaded. Spain lists Portugal but not the other way
+// round.
+func testGraph() *neighbourGraph {
+	return buildNeighbourGraph([]Country{
+		{Cca3: "PRT"},
+		{Cca3: "ESP", Borders: []string{"PRT", "FRA"}},
+		{Cca3: "FRA", Borders: []string{"ESP", "DEU", "AND"}},
+		{Cca3: "DEU", Borders: []string{"FRA", "POL"}},
+		{Cca3: "POL", Borders: []string{"DEU"}},
+		{Cca3: "GBR", Borders: []string{"IRL"}},
+		{Cca3: "IRL", Borders: []string{"GBR"}},
+		{Cca3: "ISL"},
+		{Cca3: "JPN"},
+	})
+}
+
+func TestNeighbourGraphShortestPath(t *testing.T) {
+	g := testGraph()
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{"across the mainland", "PRT", "POL", []string{"PRT", "ESP", "FRA", "DEU", "POL"}},
+		{"the other way", "POL", "PRT", []string{"POL", "DEU", "FRA", "ESP", "PRT"}},
+		{"neighbours", "GBR", "IRL", []string{"GBR", "IRL"}},
+		{"same country", "ISL", "ISL", []string{"ISL"}},
+		{"no land route", "PRT", "GBR", nil},
+		{"between islands", "ISL", "JPN", nil},
+		{"country not loaded", "FRA", "AND", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.shortestPath(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shortestPath(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeighbourGraphComponents(t *testing.T) {
+	want := [][]string{
+		{"DEU", "ESP", "FRA", "POL", "PRT"},
+		{"GBR", "IRL"},
+		{"ISL"},
+		{"JPN"},
+	}
+	if got := testGraph().components(); !reflect.DeepEqual(got, want) {
+		t.Errorf("components() = %v, want %v", got, want)
+	}
+}
+
+func TestNeighbourTimeDifferenceFollowsDST(t *testing.T) {
+	countries := []Country{
+		{Name: "Ukraine", Cca3: "UKR", IANAZone: "Europe/Kyiv", TimeZone: 120, Borders: []string{"BLR"}},
+		{Name: "Belarus", Cca3: "BLR", IANAZone: "Europe/Minsk", TimeZone: 180, Borders: []string{"UKR"}},
+	}
+	resolveNeighbours(countries)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"winter", time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC), 60},
+		{"summer", time.Date(2025, time.July, 15, 12, 0, 0, 0, time.UTC), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ukraine := withLocalTimes(countries, tt.at)[0]
+			if len(ukraine.Neighbours) != 1 {
+				t.Fatalf("got %d neighbours, want 1", len(ukraine.Neighbours))
+			}
+			if got := ukraine.Neighbours[0].TimeDifferenceMinutes; got != tt.want {
+				t.Errorf("Belarus from Ukraine = %d minutes, want %d", got, tt.want)
+			}
+			if got := newNeighbour(countries[1], countries[0], tt.at).TimeDifferenceMinutes; got != -tt.want {
+				t.Errorf("Ukraine from Belarus = %d minutes, want %d", got, -tt.want)
+			}
+		})
+	}
+	if countries[0].Neighbours[0].TimeDifference != "" {
+		t.Error("withLocalTimes changed the shared neighbours")
+	}
+}
@@ -1,25 +1,38 @@
 package src
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"html/template"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var templateFuncs = template.FuncMap{
-	"subtract": func(a, b int) int { return a - b },
-	"add":      func(a, b int) int { return a + b },
+	"subtract":       func(a, b int) int { return a - b },
+	"add":            func(a, b int) int { return a + b },
+	"formatAge":      formatAge,
+	"formatDayShift": formatDayShift,
+	"aggregateTable": newAggregateTable,
+	"formatNumber":   formatNumber,
+	"round":          func(f float64) int { return int(math.Round(f)) },
+	"title":          strings.Title,
 }
 
 func handleHome(w http.ResponseWriter, r *http.Request) {
 	// First, validate all query parameters
 	queryParams := r.URL.Query()
-	validParams := []string{"q", "region", "timezone", "timerange", "page"}
+	validParams := []string{"q", "at", "region", "timezone", "timerange", "timerange_zone", "language", "currency", "page",
+		"hdi_category", "hdi_min", "hdi_max", "life_min", "life_max",
+		"expected_schooling_min", "expected_schooling_max", "mean_schooling_min", "mean_schooling_max"}
+	validParams = append(validParams, indicatorParams...)
 
 	// Check if there are any invalid parameters
 	for param := range queryParams {
@@ -39,21 +52,46 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
 	}
 
 	query := r.URL.Query().Get("q")
-	region := r.URL.Query().Get("region")
-	timezone := r.URL.Query().Get("timezone")
-	timeRange := r.URL.Query().Get("timerange")
+	filter, badParam := newCountryFilter(r.URL.Query())
+	if badParam != "" {
+		http.Redirect(w, r, "/error?type=invalid_param&param="+badParam, http.StatusSeeOther)
+		return
+	}
+	at, err := requestTime(r)
+	if err != nil {
+		http.Redirect(w, r, "/error?type=invalid_param&param=at", http.StatusSeeOther)
+		return
+	}
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	if page < 1 {
 		page = 1
 	}
 
-	filteredCountries := filterCountries(allCountries, region, timezone, timeRange, w, r)
+	current := currentData.Load()
+	if current == nil {
+		renderDataUnavailable(w, "home.html")
+		return
+	}
+	countries := withLocalTimes(current.countries, at)
+
+	filteredCountries := filterCountries(countries, filter, w, r)
 	if filteredCountries == nil {
 		return
 	}
 
 	searchedCountries := searchCountries(filteredCountries, query)
 
+	indicatorQuery := url.Values{}
+	for _, param := range indicatorParams {
+		if value := r.URL.Query().Get(param); value != "" {
+			indicatorQuery.Set(param, value)
+		}
+	}
+	if searchedCountries, err = queryIndicators(searchedCountries, current.indicators, indicatorQuery); err != nil {
+		http.Redirect(w, r, "/error?type=invalid_param&param=indicator", http.StatusSeeOther)
+		return
+	}
+
 	// Check if search query was provided and no results were found
 	if query != "" && len(searchedCountries) == 0 {
 		http.Redirect(w, r, "/error?type=search&query="+query, http.StatusSeeOther)
@@ -75,24 +113,51 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
 		paginatedCountries[i].IsFavorite = contains(favorites.Countries, paginatedCountries[i].Name)
 	}
 
-	regions := getUniqueRegions(allCountries)
-	timeZones := getUniqueTimeZones(allCountries)
+	regions := getUniqueRegions(countries)
+	timeZones := getUniqueTimeZones(countries)
 
 	data := PageData{
-		Countries:    paginatedCountries,
-		Query:        query,
-		Regions:      regions,
-		TimeZones:    timeZones,
-		CurrentPage:  page,
-		TotalPages:   totalPages,
-		Region:       region,
-		TimeZone:     timezone,
-		TimeRange:    timeRange,
-		ItemsPerPage: itemsPerPage,
+		Countries:            paginatedCountries,
+		Query:                query,
+		Regions:              regions,
+		TimeZones:            timeZones,
+		CurrentPage:          page,
+		TotalPages:           totalPages,
+		Region:               filter.Region,
+		TimeZone:             filter.TimeZone,
+		TimeRange:            filter.TimeRange,
+		TimeRangeZone:        filter.TimeRangeZone,
+		Languages:            current.languages,
+		Currencies:           current.currencies,
+		Language:             filter.Language,
+		Currency:             filter.Currency,
+		HDICategory:          filter.HDICategory,
+		HDICategories:        hdiCategories,
+		HDIMin:               filter.HDIMin,
+		HDIMax:               filter.HDIMax,
+		LifeMin:              filter.LifeMin,
+		LifeMax:              filter.LifeMax,
+		ExpectedSchoolingMin: filter.ExpectedSchoolingMin,
+		ExpectedSchoolingMax: filter.ExpectedSchoolingMax,
+		MeanSchoolingMin:     filter.MeanSchoolingMin,
+		MeanSchoolingMax:     filter.MeanSchoolingMax,
+		At:                   r.URL.Query().Get("at"),
+		Instant:              formatInstant(at),
+		Indicators:           current.indicators,
+		Indicator:            indicatorQuery.Get("indicator"),
+		IndicatorMin:         indicatorQuery.Get("min"),
+		IndicatorMax:         indicatorQuery.Get("max"),
+		Sort:                 indicatorQuery.Get("sort"),
+		Order:                indicatorQuery.Get("order"),
+		FilterQuery:          buildFilterQuery(query, r.URL.Query().Get("at"), filter, indicatorQuery),
+		ItemsPerPage:         itemsPerPage,
+		Aggregates:           current.aggregates,
+		HDIFootnotes:         current.hdiFootnotes,
+		DataSource:           current.source,
 	}
 
 	tmpl := template.New("home.html").Funcs(templateFuncs)
-	tmpl, err := tmpl.ParseFiles("templates/home.html")
+	tmpl, err = tmpl.ParseFiles("templates/home.html")
 	if err != nil {
 		log.Printf("Error parsing template: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -108,8 +173,20 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleFavorites(w http.ResponseWriter, r *http.Request) {
+	current := currentData.Load()
+	if current == nil {
+		renderDataUnavailable(w, "favorites.html")
+		return
+	}
+
+	at, err := requestTime(r)
+	if err != nil {
+		http.Redirect(w, r, "/error?type=invalid_param&param=at", http.StatusSeeOther)
+		return
+	}
+
 	var favoriteCountries []Country
-	for _, country := range allCountries {
+	for _, country := range current.countries {
 		if contains(favorites.Countries, country.Name) {
 			country.IsFavorite = true
 			favoriteCountries = append(favoriteCountries, country)
@@ -117,10 +194,14 @@ func handleFavorites(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := PageData{
-		Countries: favoriteCountries,
+		Countries:    withLocalTimes(favoriteCountries, at),
+		HDIFootnotes: current.hdiFootnotes,
+		At:           r.URL.Query().Get("at"),
+		Instant:      formatInstant(at),
 	}
 
-	tmpl, err := template.ParseFiles("templates/favorites.html")
+	tmpl := template.New("favorites.html").Funcs(templateFuncs)
+	tmpl, err = tmpl.ParseFiles("templates/favorites.html")
 	if err != nil {
 		log.Printf("Error parsing template: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -285,9 +366,421 @@ func handleMap(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleMeeting renders the meeting planner. The form submits the same
+// parameters as /api/meeting, with participants one per line.
+func handleMeeting(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	data := struct {
+		Participants string
+		Hours        string
+		From         string
+		To           string
+		Duration     string
+		Plan         *MeetingPlan
+		Error        string
+	}{
+		Participants: strings.Join(append(query["participant"], query.Get("participants")), "\n"),
+		Hours:        query.Get("hours"),
+		From:         query.Get("from"),
+		To:           query.Get("to"),
+		Duration:     query.Get("duration"),
+	}
+	data.Participants = strings.TrimSpace(data.Participants)
+
+	if data.Participants != "" {
+		plan, err := meetingPlan(query)
+		if err != nil {
+			data.Error = err.Error()
+		} else {
+			data.Plan = &plan
+		}
+	}
+
+	tmpl, err := template.ParseFiles("templates/meeting.html")
+	if err != nil {
+		log.Printf("Error parsing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func handleMeetingAPI(w http.ResponseWriter, r *http.Request) {
+	plan, err := meetingPlan(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+func handleConvertAPI(w http.ResponseWriter, r *http.Request) {
+	var countries []Country
+	if data := currentData.Load(); data != nil {
+		countries = data.countries
+	}
+	conversion, err := convertTime(countries, r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, conversion)
+}
+
+// meetingPlan plans a meeting from query parameters. Countries are looked up
+// in the current dataset; zones and offsets work without it.
+func meetingPlan(query url.Values) (MeetingPlan, error) {
+	var countries []Country
+	if data := currentData.Load(); data != nil {
+		countries = data.countries
+	}
+	request, err := parseMeetingRequest(countries, query, now().UTC())
+	if err != nil {
+		return MeetingPlan{}, err
+	}
+	return planMeeting(request), nil
+}
+
 func handleCountriesAPI(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(allCountries)
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+
+	filter, badParam := newCountryFilter(r.URL.Query())
+	if badParam != "" {
+		writeJSONError(w, http.StatusBadRequest, "invalid "+badParam+": "+r.URL.Query().Get(badParam))
+		return
+	}
+
+	at, err := requestTime(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	countries, err := queryIndicators(filter.apply(withLocalTimes(data.countries, at)), data.indicators, r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !data.source.FetchedAt.IsZero() {
+		w.Header().Set("Last-Modified", data.source.FetchedAt.UTC().Format(http.TimeFormat))
+	}
+	json.NewEncoder(w).Encode(countries)
+}
+
+func handleAggregatesAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+
+	switch by := r.URL.Query().Get("by"); by {
+	case "":
+		writeJSON(w, http.StatusOK, data.aggregates)
+	case "region":
+		writeJSON(w, http.StatusOK, data.aggregates.Regions)
+	case "category":
+		writeJSON(w, http.StatusOK, data.aggregates.Categories)
+	default:
+		writeJSONError(w, http.StatusBadRequest, "by must be region or category, got "+by)
+	}
+}
+
+func handleIndicatorsAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+
+	indicators := data.indicators
+	if indicators == nil {
+		indicators = []Indicator{}
+	}
+	writeJSON(w, http.StatusOK, indicators)
+}
+
+func handleLanguagesAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+
+	if code := r.URL.Query().Get("code"); code != "" {
+		for _, entry := range data.languages {
+			if strings.EqualFold(entry.Code, code) {
+				writeJSON(w, http.StatusOK, entry)
+				return
+			}
+		}
+		writeJSONError(w, http.StatusNotFound, "unknown language: "+code)
+		return
+	}
+	writeJSON(w, http.StatusOK, data.languages)
+}
+
+func handleCurrenciesAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+
+	if code := r.URL.Query().Get("code"); code != "" {
+		for _, entry := range data.currencies {
+			if strings.EqualFold(entry.Code, code) {
+				writeJSON(w, http.StatusOK, entry)
+				return
+			}
+		}
+		writeJSONError(w, http.StatusNotFound, "unknown currency: "+code)
+		return
+	}
+	writeJSON(w, http.StatusOK, data.currencies)
+}
+
+// requestTime returns the instant a page or API request shows: the at
+// parameter when given (see parseInstant), otherwise now.
+func requestTime(r *http.Request) (time.Time, error) {
+	if value := r.URL.Query().Get("at"); value != "" {
+		return parseInstant(value)
+	}
+	return now(), nil
+}
+
+// formatInstant renders the instant of a page in UTC.
+func formatInstant(at time.Time) string {
+	return at.UTC().Format("Mon 2 Jan 2006 15:04 UTC")
+}
+
+// buildFilterQuery encodes the search, instant, filters and indicator
+// parameters of the home page so that pagination and favorite links keep
+// them.
+func buildFilterQuery(query, at string, filter countryFilter, indicatorQuery url.Values) string {
+	values := url.Values{}
+	for param := range indicatorQuery {
+		values.Set(param, indicatorQuery.Get(param))
+	}
+	params := filter.params()
+	params["q"] = query
+	params["at"] = at
+	for key, value := range params {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	return values.Encode()
+}
+
+func handleDataQualityAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+
+	report := data.quality
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		report = report.filter(kind)
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func handleHDIReconciliationAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, data.reconciliation)
+}
+
+func handleHDIHistoryAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+
+	country, ok := findCountry(data.countries, r.URL.Query().Get("country"))
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "unknown country: "+r.URL.Query().Get("country"))
+		return
+	}
+
+	series := data.hdiHistory[country.Cca3]
+	if series == nil {
+		series = []HDIPoint{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"code":   country.Cca3,
+		"name":   country.Name,
+		"flag":   country.Flag,
+		"series": series,
+	})
+}
+
+func handleHDIFootnotesAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, data.hdiFootnotes)
+}
+
+func handleNeighboursAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+
+	country, ok := findCountry(data.countries, r.URL.Query().Get("country"))
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "unknown country: "+r.URL.Query().Get("country"))
+		return
+	}
+	at, err := requestTime(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"code":       country.Cca3,
+		"name":       country.Name,
+		"flag":       country.Flag,
+		"timezone":   country.TimeZone,
+		"neighbours": neighboursAt(country, at),
+	})
+}
+
+func handleNeighbourPathAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+
+	from, ok := findCountry(data.countries, r.URL.Query().Get("from"))
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "unknown country: "+r.URL.Query().Get("from"))
+		return
+	}
+	to, ok := findCountry(data.countries, r.URL.Query().Get("to"))
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "unknown country: "+r.URL.Query().Get("to"))
+		return
+	}
+
+	at, err := requestTime(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes := data.graph.shortestPath(from.Cca3, to.Cca3)
+	if codes == nil {
+		writeJSONError(w, http.StatusNotFound, "no land route between "+from.Name+" and "+to.Name)
+		return
+	}
+
+	// Each step carries the time difference from the previous country
+	steps := []Neighbour{{Code: from.Cca3, Name: from.Name, Flag: from.Flag, TimeDifference: formatTimeDifference(0)}}
+	previous := from
+	for _, code := range codes[1:] {
+		next, _ := findCountry(data.countries, code)
+		steps = append(steps, newNeighbour(previous, next, at))
+		previous = next
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"from":            from.Cca3,
+		"to":              to.Cca3,
+		"borders_crossed": len(codes) - 1,
+		"path":            steps,
+	})
+}
+
+func handleComponentsAPI(w http.ResponseWriter, r *http.Request) {
+	data := currentData.Load()
+	if data == nil {
+		writeDataUnavailableJSON(w)
+		return
+	}
+
+	type component struct {
+		Size      int      `json:"size"`
+		Countries []string `json:"countries"`
+	}
+	var components []component
+	for _, codes := range data.graph.components() {
+		components = append(components, component{Size: len(codes), Countries: codes})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"count":      len(components),
+		"components": components,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func handleRefreshAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Without a token the endpoint does not exist
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	data, err := dataRefresher.refresh()
+	if err != nil {
+		log.Printf("Manual refresh failed: %v", err)
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"status": "error", "error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "ok",
+		"countries":   len(data.countries),
+		"source":      data.source,
+		"age_seconds": int(data.source.Age().Seconds()),
+		"loaded_at":   data.loadedAt.UTC().Format(time.RFC3339),
+	})
 }
 
 func handleTimezoneBorders(w http.ResponseWriter, r *http.Request) {
@@ -347,6 +840,50 @@ func handleTimezoneBorders(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// renderDataUnavailable renders a page in its "data unavailable" state while
+// the server is running without country data.
+func renderDataUnavailable(w http.ResponseWriter, name string) {
+	lastAttempt, lastErr := dataRefresher.status()
+	data := PageData{
+		DataUnavailable: true,
+		LastAttempt:     lastAttempt,
+	}
+	if lastErr != nil {
+		data.LoadError = lastErr.Error()
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).ParseFiles("templates/" + name)
+	if err != nil {
+		log.Printf("Error parsing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(degradedRetryDelay.Seconds())))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+func writeDataUnavailableJSON(w http.ResponseWriter) {
+	lastAttempt, lastErr := dataRefresher.status()
+	response := map[string]interface{}{
+		"error": "country data unavailable",
+	}
+	if !lastAttempt.IsZero() {
+		response["last_attempt"] = lastAttempt.UTC().Format(time.RFC3339)
+	}
+	if lastErr != nil {
+		response["reason"] = lastErr.Error()
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(degradedRetryDelay.Seconds())))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(response)
+}
+
 func handleNotFound(w http.ResponseWriter, r *http.Request) {
 	errorData := struct {
 		ErrorTitle   string
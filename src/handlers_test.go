@@ -0,0 +1,214 @@
+package src
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func rawCountry(name, cca2, cca3, region, zone string) RawCountry {
+	var country RawCountry
+	country.Name.Common = name
+	country.Cca2 = cca2
+	country.Cca3 = cca3
+	country.Region = region
+	country.TimeZones = []string{zone}
+	country.Population = 1000000
+	country.Area = 1000
+	return country
+}
+
+// loadMemoryData makes the countries the current dataset for the duration of
+// the test.
+func loadMemoryData(t *testing.T, countries ...RawCountry) {
+	t.Helper()
+	data, err := fetchCountries(newMemoryProvider(countries...))
+	if err != nil {
+		t.Fatalf("fetchCountries() error = %v", err)
+	}
+	previous := currentData.Swap(data)
+	t.Cleanup(func() { currentData.Store(previous) })
+}
+
+func TestHandleCountriesAPIServesMemoryProvider(t *testing.T) {
+	loadMemoryData(t,
+		rawCountry("Switzerland", "CH", "CHE", "Europe", "UTC+01:00"),
+		rawCountry("India", "IN", "IND", "Asia", "UTC+05:30"),
+		rawCountry("Germany", "DE", "DEU", "Europe", "UTC+01:00"),
+	)
+
+	tests := []struct {
+		name   string
+		target string
+		want   []string
+	}{
+		{"all countries", "/api/countries", []string{"Switzerland", "India", "Germany"}},
+		{"region filter", "/api/countries?region=Asia", []string{"India"}},
+		{"time zone filter", "/api/countries?timezone=UTC%2B05:30", []string{"India"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handleCountriesAPI(recorder, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+			}
+
+			var countries []Country
+			if err := json.NewDecoder(recorder.Body).Decode(&countries); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, country := range countries {
+				names = append(names, country.Name)
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("got %v, want %v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", names, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleCountriesAPIRejectsBadFilter(t *testing.T) {
+	loadMemoryData(t, rawCountry("Switzerland", "CH", "CHE", "Europe", "UTC+01:00"))
+
+	recorder := httptest.NewRecorder()
+	handleCountriesAPI(recorder, httptest.NewRequest(http.MethodGet, "/api/countries?hdi_min=high", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleCountriesAPIWithoutData(t *testing.T) {
+	previous := currentData.Swap(nil)
+	t.Cleanup(func() { currentData.Store(previous) })
+	previousRefresher := dataRefresher
+	dataRefresher = newRefresher(newMemoryProvider(), 0)
+	t.Cleanup(func() { dataRefresher = previousRefresher })
+
+	recorder := httptest.NewRecorder()
+	handleCountriesAPI(recorder, httptest.NewRequest(http.MethodGet, "/api/countries", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusServiceUnavailable)
+	}
+	if recorder.Header().Get("Retry-After") == "" {
+		t.Error("no Retry-After header")
+	}
+}
+
+func TestHandleRefreshAPIRequiresToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{"no token configured", "", "Bearer ", http.StatusNotFound},
+		{"missing header", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer secrets", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ADMIN_TOKEN", tt.token)
+			request := httptest.NewRequest(http.MethodPost, "/api/admin/refresh", nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			handleRefreshAPI(recorder, request)
+			if recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.want)
+			}
+		})
+	}
+}
+
+// slowProvider closes started when a load begins and blocks until release
+// is closed, like an upstream that is slow to answer.
+type slowProvider struct {
+	started, release chan struct{}
+}
+
+func (p slowProvider) Name() string {
+	return "slow provider"
+}
+
+func (p slowProvider) Countries() ([]RawCountry, SourceInfo, error) {
+	close(p.started)
+	<-p.release
+	return nil, SourceInfo{Name: p.Name()}, errors.New("gave up")
+}
+
+func TestHandleCountriesAPIAnswersDuringSlowLoad(t *testing.T) {
+	previous := currentData.Swap(nil)
+	t.Cleanup(func() { currentData.Store(previous) })
+	provider := slowProvider{started: make(chan struct{}), release: make(chan struct{})}
+	previousRefresher := dataRefresher
+	dataRefresher = newRefresher(provider, 0)
+	t.Cleanup(func() { dataRefresher = previousRefresher })
+
+	loaded := make(chan struct{})
+	go func() {
+		dataRefresher.refresh()
+		close(loaded)
+	}()
+	<-provider.started
+
+	done := make(chan int)
+	go func() {
+		recorder := httptest.NewRecorder()
+		handleCountriesAPI(recorder, httptest.NewRequest(http.MethodGet, "/api/countries", nil))
+		done <- recorder.Code
+	}()
+	select {
+	case code := <-done:
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want %d", code, http.StatusServiceUnavailable)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("request waited for the load in progress")
+	}
+	close(provider.release)
+	<-loaded
+}
+
+func TestHandleCountriesAPIUsesInjectedClock(t *testing.T) {
+	previousNow := now
+	now = func() time.Time { return time.Date(2025, time.July, 15, 22, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = previousNow })
+	loadMemoryData(t,
+		rawCountry("India", "IN", "IND", "Asia", "UTC+05:30"),
+		rawCountry("Iceland", "IS", "ISL", "Europe", "UTC"),
+	)
+
+	recorder := httptest.NewRecorder()
+	handleCountriesAPI(recorder, httptest.NewRequest(http.MethodGet, "/api/countries", nil))
+	var countries []Country
+	if err := json.NewDecoder(recorder.Body).Decode(&countries); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"India": "2025-07-16 03:30", "Iceland": "2025-07-15 22:00"}
+	for _, country := range countries {
+		if got := country.CurrentDate + " " + country.CurrentTime; got != want[country.Name] {
+			t.Errorf("%s: local time %s, want %s", country.Name, got, want[country.Name])
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	handleCountriesAPI(recorder, httptest.NewRequest(http.MethodGet, "/api/countries?timerange=night", nil))
+	countries = nil
+	if err := json.NewDecoder(recorder.Body).Decode(&countries); err != nil {
+		t.Fatal(err)
+	}
+	if len(countries) != 1 || countries[0].Name != "India" {
+		t.Errorf("night filter matched %v, want only India", countries)
+	}
+}
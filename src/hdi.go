@@ -0,0 +1,397 @@
+package src
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Column titles of the HDI annex table, lower-cased
+const (
+	hdiColValue        = "human development index (hdi)"
+	hdiColLife         = "life expectancy at birth"
+	hdiColExpected     = "expected years of schooling"
+	hdiColMean         = "mean years of schooling"
+	hdiColGNI          = "gross national income (gni) per capita"
+	hdiColGNIMinusRank = "gni per capita rank minus hdi rank"
+	hdiColRank         = "hdi rank"
+	hdiColCountry      = "country"
+	hdiColISO3         = "iso3"
+)
+
+// hdiColumns records where each value sits in the annex. The table header
+// spans three rows: column titles, then "HDI rank, Country, ...", then the
+// year each column refers to.
+type hdiColumns struct {
+	rank, country, iso3              int
+	value, life, expected, mean, gni int
+	gniMinusRank, previousRank       int
+	year, previousRankYear           int
+
+	// Footnote marker column of each value, keyed like HDIData.Notes, and
+	// the markers the year row puts on whole columns
+	markers       map[string]int
+	columnMarkers map[string][]string
+}
+
+var footnotePattern = regexp.MustCompile(`^([a-z]{1,2})\. (.+)$`)
+
+// parseHDIData reads the HDI annex CSV and returns the rows keyed by country
+// name, along with the footnote legend printed below the table. Columns are
+// located by their header titles rather than position.
+func parseHDIData(csvContent string) (map[string]HDIData, HDIFootnotes, error) {
+	hdiMap := make(map[string]HDIData)
+	footnotes := HDIFootnotes{Legend: make(map[string]string)}
+
+	reader := csv.NewReader(strings.NewReader(csvContent))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var cols *hdiColumns
+	var titles []string
+	var currentCategory string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return hdiMap, footnotes, err
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+
+		// Locate the header before reading any country rows
+		if cols == nil {
+			switch {
+			case indexOf(record, hdiColLife) >= 0:
+				titles = record
+			case titles != nil && indexOf(record, hdiColCountry) >= 0:
+				cols = newHDIColumns(titles, record)
+			}
+			continue
+		}
+		if cols.year == 0 {
+			cols.year = atoiOrZero(field(record, cols.value))
+			cols.previousRankYear = atoiOrZero(field(record, cols.previousRank))
+			for key, column := range cols.markers {
+				if markers := splitMarkers(field(record, column)); markers != nil {
+					cols.columnMarkers[key] = markers
+				}
+			}
+			continue
+		}
+
+		rank := field(record, cols.rank)
+		name := field(record, cols.country)
+		if rank == "" {
+			if m := footnotePattern.FindStringSubmatch(name); m != nil {
+				footnotes.Legend[m[1]] = m[2]
+				continue
+			}
+			// Section rows such as "VERY HIGH HUMAN DEVELOPMENT" set the
+			// category; the regional aggregates below the table end it
+			if strings.HasSuffix(name, "HUMAN DEVELOPMENT") {
+				currentCategory = categoryName(name)
+			} else if name != "" && name != strings.ToUpper(name) {
+				currentCategory = ""
+			}
+			continue
+		}
+
+		rankNum, err := strconv.Atoi(rank)
+		if err != nil || name == "" || currentCategory == "" {
+			continue
+		}
+
+		hdiValue, ok := parseHDINumber(field(record, cols.value))
+		if !ok {
+			log.Printf("Warning: No HDI value for %s", name)
+			continue
+		}
+
+		life, _ := parseHDINumber(field(record, cols.life))
+		expected, _ := parseHDINumber(field(record, cols.expected))
+		mean, _ := parseHDINumber(field(record, cols.mean))
+		gni, _ := parseHDINumber(field(record, cols.gni))
+		gniMinusRank, _ := parseHDINumber(field(record, cols.gniMinusRank))
+		previousRank, _ := parseHDINumber(field(record, cols.previousRank))
+
+		notes := make(map[string][]string)
+		for key, column := range cols.markers {
+			markers := append(append([]string(nil), cols.columnMarkers[key]...), splitMarkers(field(record, column))...)
+			if len(markers) > 0 {
+				notes[key] = markers
+			}
+		}
+		if len(notes) == 0 {
+			notes = nil
+		}
+
+		hdiMap[name] = HDIData{
+			Name:                name,
+			ISO3:                field(record, cols.iso3),
+			HDIRank:             rankNum,
+			HDIValue:            hdiValue,
+			Category:            currentCategory,
+			Year:                cols.year,
+			LifeExpect:          life,
+			ExpectedSchooling:   expected,
+			MeanSchooling:       mean,
+			GNIPerCapita:        gni,
+			GNIRankMinusHDIRank: int(gniMinusRank),
+			PreviousRank:        int(previousRank),
+			PreviousRankYear:    cols.previousRankYear,
+			Notes:               notes,
+		}
+	}
+
+	if cols == nil {
+		return hdiMap, footnotes, errors.New("HDI header row not found")
+	}
+	footnotes.Year = cols.year
+	log.Printf("Parsed HDI data for %d countries (%d)", len(hdiMap), cols.year)
+	return hdiMap, footnotes, nil
+}
+
+func newHDIColumns(titles, labels []string) *hdiColumns {
+	cols := &hdiColumns{
+		rank:         indexOf(labels, hdiColRank),
+		iso3:         max(indexOf(labels, hdiColISO3), indexOf(titles, hdiColISO3)),
+		country:      indexOf(labels, hdiColCountry),
+		value:        indexOf(titles, hdiColValue),
+		life:         indexOf(titles, hdiColLife),
+		expected:     indexOf(titles, hdiColExpected),
+		mean:         indexOf(titles, hdiColMean),
+		gni:          indexOf(titles, hdiColGNI),
+		gniMinusRank: indexOf(titles, hdiColGNIMinusRank),
+		previousRank: indexOf(titles, hdiColRank),
+	}
+	// The HDI value column has no title of its own in some editions
+	if cols.value < 0 {
+		cols.value = cols.country + 1
+	}
+
+	// Footnote markers sit in the untitled column right after a value
+	cols.markers = make(map[string]int)
+	cols.columnMarkers = make(map[string][]string)
+	for key, column := range map[string]int{
+		"hdi_value":               cols.value,
+		"life_expectancy":         cols.life,
+		"expected_schooling":      cols.expected,
+		"mean_schooling":          cols.mean,
+		"gni_per_capita":          cols.gni,
+		"gni_rank_minus_hdi_rank": cols.gniMinusRank,
+	} {
+		if column >= 0 && field(titles, column+1) == "" && field(labels, column+1) == "" {
+			cols.markers[key] = column + 1
+		}
+	}
+	return cols
+}
+
+// indexOf finds a header cell, ignoring case and surrounding spaces.
+func indexOf(record []string, title string) int {
+	for i, cell := range record {
+		if strings.EqualFold(strings.TrimSpace(cell), title) {
+			return i
+		}
+	}
+	return -1
+}
+
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// parseHDINumber parses values such as "0.967", "69,433" or "-3". Missing
+// values are written as ".." or "—" in the annex.
+func parseHDINumber(value string) (float64, bool) {
+	value = strings.ReplaceAll(value, ",", "")
+	if value == "" || value == ".." || value == "—" {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+// splitMarkers turns a footnote cell such as "e,g" into its markers.
+func splitMarkers(cell string) []string {
+	var markers []string
+	for _, marker := range strings.Split(cell, ",") {
+		if marker = strings.TrimSpace(marker); marker != "" {
+			markers = append(markers, marker)
+		}
+	}
+	return markers
+}
+
+func atoiOrZero(value string) int {
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+// categoryName turns "VERY HIGH HUMAN DEVELOPMENT" into "Very high human development".
+func categoryName(section string) string {
+	lower := strings.ToLower(section)
+	return strings.ToUpper(lower[:1]) + lower[1:]
+}
+
+// hdiAnnex is one Human Development Report annex, covering a single year.
+type hdiAnnex struct {
+	file string
+	year int
+	rows map[string]HDIData
+
+	footnotes HDIFootnotes
+}
+
+// loadHDIAnnexes parses every CSV annex in dir and returns them oldest year
+// first. When two files cover the same year the later file name wins.
+func loadHDIAnnexes(dir string) ([]hdiAnnex, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no HDI annex files in %s", dir)
+	}
+	sort.Strings(files)
+
+	byYear := make(map[int]hdiAnnex)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Warning: Could not read HDI annex %s: %v", file, err)
+			continue
+		}
+		rows, footnotes, err := parseHDIData(string(content))
+		if err != nil || len(rows) == 0 {
+			log.Printf("Warning: Skipping HDI annex %s: %v", file, err)
+			continue
+		}
+
+		annex := hdiAnnex{file: file, rows: rows, footnotes: footnotes}
+		for _, row := range rows {
+			annex.year = row.Year
+			break
+		}
+		if previous, ok := byYear[annex.year]; ok {
+			log.Printf("Warning: %s and %s both cover %d, using %s", previous.file, file, annex.year, file)
+		}
+		byYear[annex.year] = annex
+	}
+
+	annexes := make([]hdiAnnex, 0, len(byYear))
+	for _, annex := range byYear {
+		annexes = append(annexes, annex)
+	}
+	sort.Slice(annexes, func(i, j int) bool { return annexes[i].year < annexes[j].year })
+	return annexes, nil
+}
+
+// mergeHDI reconciles every annex with the countries and returns each
+// country's series keyed by cca3. Countries get the most recent annex as
+// their HDI, and the reconciliation report of that annex is returned.
+func mergeHDI(countries []Country, annexes []hdiAnnex, aliases map[string]string) (map[string][]HDIPoint, ReconciliationReport) {
+	history := make(map[string][]HDIPoint)
+	if len(annexes) == 0 {
+		return history, reconcileHDI(countries, nil, aliases)
+	}
+
+	var report ReconciliationReport
+	for i, annex := range annexes {
+		report = reconcileHDI(countries, annex.rows, aliases)
+		latest := i == len(annexes)-1
+		for _, match := range report.Matches {
+			row := annex.rows[match.HDIName]
+			if latest {
+				countries[match.index].HDI = row
+			}
+			if match.Code != "" {
+				history[match.Code] = append(history[match.Code], newHDIPoint(row, history[match.Code]))
+			}
+		}
+	}
+	return history, report
+}
+
+func newHDIPoint(row HDIData, series []HDIPoint) HDIPoint {
+	point := HDIPoint{
+		Year:              row.Year,
+		HDIValue:          row.HDIValue,
+		HDIRank:           row.HDIRank,
+		Category:          row.Category,
+		LifeExpect:        row.LifeExpect,
+		ExpectedSchooling: row.ExpectedSchooling,
+		MeanSchooling:     row.MeanSchooling,
+		GNIPerCapita:      row.GNIPerCapita,
+		Notes:             row.Notes,
+	}
+	if len(series) > 0 {
+		previous := series[len(series)-1]
+		point.PreviousYear = previous.Year
+		point.Change = math.Round((row.HDIValue-previous.HDIValue)*1000) / 1000
+		if years := row.Year - previous.Year; years > 0 {
+			point.AnnualChange = math.Round((row.HDIValue-previous.HDIValue)/float64(years)*10000) / 10000
+		}
+	}
+	return point
+}
+
+// rankHDI ranks countries by HDI value within their region and computes the
+// percentile of each among all countries with HDI data, i.e. the share of
+// them with a lower value. Equal values share a rank.
+func rankHDI(countries []Country) {
+	var ranked []int
+	byRegion := make(map[string][]int)
+	for i, country := range countries {
+		if country.HDI.HDIValue > 0 {
+			ranked = append(ranked, i)
+			byRegion[country.Region] = append(byRegion[country.Region], i)
+		}
+	}
+
+	for _, indexes := range byRegion {
+		sort.SliceStable(indexes, func(a, b int) bool {
+			return countries[indexes[a]].HDI.HDIValue > countries[indexes[b]].HDI.HDIValue
+		})
+		for position, i := range indexes {
+			rank := position + 1
+			if position > 0 && countries[i].HDI.HDIValue == countries[indexes[position-1]].HDI.HDIValue {
+				rank = countries[indexes[position-1]].HDIRegionRank
+			}
+			countries[i].HDIRegionRank = rank
+			countries[i].HDIRegionCount = len(indexes)
+		}
+	}
+
+	// Ascending order, so the position of the first equal value is the
+	// number of countries below it
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return countries[ranked[a]].HDI.HDIValue < countries[ranked[b]].HDI.HDIValue
+	})
+	below := 0
+	for position, i := range ranked {
+		if position > 0 && countries[i].HDI.HDIValue != countries[ranked[position-1]].HDI.HDIValue {
+			below = position
+		}
+		countries[i].HDIPercentile = math.Round(1000*float64(below)/float64(len(ranked))) / 10
+	}
+}
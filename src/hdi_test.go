@@ -0,0 +1,188 @@
+package src
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHDIData(t *testing.T) {
+	// The annex layout, with the schooling columns swapped and an ISO3
+	// column added, as in other editions of the table
+	const annex = `,Table 1. Human Development Index and its components,,,,,,,,,,,,,,
+,,,,,,,,,,,,,,,
+,,,Human Development Index (HDI),,Life expectancy at birth,,Mean years of schooling,,Expected years of schooling,,Gross national income (GNI) per capita,,GNI per capita rank minus HDI rank,,HDI rank
+HDI rank,Country,ISO3,Value,,(years),,(years),,(years),,(2017 PPP $),,,,
+,,,2022,,2022,,2022,,2022,,2022,,2022,,2021
+,VERY HIGH HUMAN DEVELOPMENT,,,,,,,,,,,,,,
+1,Switzerland,CHE,0.967,,84.3,,13.9,,16.6,,"69,433",,6,,1
+4,"Hong Kong, China (SAR)",HKG,0.956,,84.3,,12.3,,17.8,,"62,486",,6,,3
+,LOW HUMAN DEVELOPMENT,,,,,,,,,,,,,,
+193,Somalia,SOM,0.380,,56.1,,..,,7.6,,"1,104",,-5,,193
+,Developing countries,,0.694,,70.5,,7.6,,12.4,,"11,574",,—,,—
+`
+	rows, footnotes, err := parseHDIData(annex)
+	if err != nil {
+		t.Fatalf("parseHDIData() error = %v", err)
+	}
+	if footnotes.Year != 2022 {
+		t.Errorf("year = %d, want 2022", footnotes.Year)
+	}
+
+	tests := []struct {
+		name string
+		want HDIData
+	}{
+		{"Switzerland", HDIData{ISO3: "CHE", HDIRank: 1, HDIValue: 0.967, Category: "Very high human development",
+			LifeExpect: 84.3, ExpectedSchooling: 16.6, MeanSchooling: 13.9, GNIPerCapita: 69433,
+			GNIRankMinusHDIRank: 6, PreviousRank: 1}},
+		{"Hong Kong, China (SAR)", HDIData{ISO3: "HKG", HDIRank: 4, HDIValue: 0.956, Category: "Very high human development",
+			LifeExpect: 84.3, ExpectedSchooling: 17.8, MeanSchooling: 12.3, GNIPerCapita: 62486,
+			GNIRankMinusHDIRank: 6, PreviousRank: 3}},
+		{"Somalia", HDIData{ISO3: "SOM", HDIRank: 193, HDIValue: 0.380, Category: "Low human development",
+			LifeExpect: 56.1, ExpectedSchooling: 7.6, GNIPerCapita: 1104,
+			GNIRankMinusHDIRank: -5, PreviousRank: 193}},
+	}
+	if len(rows) != len(tests) {
+		t.Errorf("got %d rows, want %d", len(rows), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := rows[tt.name]
+			if !ok {
+				t.Fatalf("no row for %q", tt.name)
+			}
+			want := tt.want
+			want.Name, want.Year, want.PreviousRankYear = tt.name, 2022, 2021
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseHDIDataWithoutHeader(t *testing.T) {
+	if _, _, err := parseHDIData("1,Switzerland,0.967\n"); err == nil {
+		t.Error("parseHDIData() accepted a table without a header")
+	}
+}
+
+func TestMergeHDIYearOverYear(t *testing.T) {
+	annex := func(year int, values map[string]float64) hdiAnnex {
+		rows := make(map[string]HDIData)
+		for code, value := range values {
+			rows[code] = HDIData{Name: code, ISO3: code, HDIValue: value, Year: year}
+		}
+		return hdiAnnex{year: year, rows: rows}
+	}
+	annexes := []hdiAnnex{
+		annex(2020, map[string]float64{"CHE": 0.955, "NOR": 0.961, "SOM": 0.378}),
+		annex(2021, map[string]float64{"CHE": 0.962, "SOM": 0.379}),
+		annex(2022, map[string]float64{"CHE": 0.967, "NOR": 0.966, "ISL": 0.959}),
+	}
+	countries := []Country{{Name: "Switzerland", Cca3: "CHE"}, {Name: "Norway", Cca3: "NOR"}, {Name: "Iceland", Cca3: "ISL"}, {Name: "Somalia", Cca3: "SOM"}}
+
+	history, _ := mergeHDI(countries, annexes, nil)
+
+	tests := []struct {
+		name         string
+		code         string
+		points       int
+		year         int
+		previousYear int
+		change       float64
+		annualChange float64
+	}{
+		{"change on the previous year", "CHE", 3, 2022, 2021, 0.005, 0.005},
+		{"previous year missing, change since the last annex", "NOR", 2, 2022, 2020, 0.005, 0.0025},
+		{"first value, no change", "ISL", 1, 2022, 0, 0, 0},
+		{"current value missing, series ends earlier", "SOM", 2, 2021, 2020, 0.001, 0.001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := history[tt.code]
+			if len(series) != tt.points {
+				t.Fatalf("got %d points, want %d: %+v", len(series), tt.points, series)
+			}
+			last := series[len(series)-1]
+			if last.Year != tt.year || last.PreviousYear != tt.previousYear || last.Change != tt.change || last.AnnualChange != tt.annualChange {
+				t.Errorf("last point %d (previous %d) change %v annual %v, want %d (previous %d) change %v annual %v",
+					last.Year, last.PreviousYear, last.Change, last.AnnualChange, tt.year, tt.previousYear, tt.change, tt.annualChange)
+			}
+		})
+	}
+
+	// Countries take their HDI from the latest annex only
+	if countries[0].HDI.HDIValue != 0.967 {
+		t.Errorf("Switzerland HDI = %v, want 0.967", countries[0].HDI.HDIValue)
+	}
+	if countries[3].HDI.HDIValue != 0 {
+		t.Errorf("Somalia HDI = %v, want none without a value in the latest annex", countries[3].HDI.HDIValue)
+	}
+}
+
+func TestParseHDIDataFootnotes(t *testing.T) {
+	// Markers sit in the untitled column after each value; the year row puts
+	// "a" on both schooling columns and "b" on the GNI rank difference
+	const annex = `,,Human Development Index (HDI) ,,Life expectancy at birth,,Expected years of schooling,,Mean years of schooling,,Gross national income (GNI) per capita,,GNI per capita rank minus HDI rank,,HDI rank
+HDI rank,Country,Value,,(years),,(years),,(years),,(2017 PPP $),,,,
+,,2022,,2022,,2022,a,2022,a,2022,,2022,b,2021
+,VERY HIGH HUMAN DEVELOPMENT,,,,,,,,,,,,,
+1,Switzerland,0.967,,84.3,,16.6,,13.9,c,"69,433",,6,,1
+2,Norway,0.966,,83.4,,18.6,d,13.1,"c,e","69,190",,6,,2
+3,Iceland,0.959,,82.8,,19.1,,13.8,,"54,688",,16,,4
+,a. Data refer to 2022 or the most recent year available.,,,,,,,,,,,,,
+,b. Based on countries for which a Human Development Index value is calculated.,,,,,,,,,,,,,
+,c. Updated by HDRO based on data from UNESCO Institute for Statistics (2023).,,,,,,,,,,,,,
+,d. Updated by HDRO based on data from UNESCO Institute for Statistics (2023).,,,,,,,,,,,,,
+,e. Based on cross-country regression.,,,,,,,,,,,,,
+`
+	rows, footnotes, err := parseHDIData(annex)
+	if err != nil {
+		t.Fatalf("parseHDIData() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		expected float64
+		mean     float64
+		notes    map[string][]string
+		markers  []string
+	}{
+		{"Switzerland", 16.6, 13.9, map[string][]string{
+			"expected_schooling":      {"a"},
+			"mean_schooling":          {"a", "c"},
+			"gni_rank_minus_hdi_rank": {"b"},
+		}, []string{"a", "b", "c"}},
+		{"Norway", 18.6, 13.1, map[string][]string{
+			"expected_schooling":      {"a", "d"},
+			"mean_schooling":          {"a", "c", "e"},
+			"gni_rank_minus_hdi_rank": {"b"},
+		}, []string{"a", "b", "c", "d", "e"}},
+		{"Iceland", 19.1, 13.8, map[string][]string{
+			"expected_schooling":      {"a"},
+			"mean_schooling":          {"a"},
+			"gni_rank_minus_hdi_rank": {"b"},
+		}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := rows[tt.name]
+			if row.ExpectedSchooling != tt.expected || row.MeanSchooling != tt.mean {
+				t.Errorf("schooling = %v, %v, want %v, %v without the markers", row.ExpectedSchooling, row.MeanSchooling, tt.expected, tt.mean)
+			}
+			if !reflect.DeepEqual(row.Notes, tt.notes) {
+				t.Errorf("notes = %v, want %v", row.Notes, tt.notes)
+			}
+			if got := row.Markers(); !reflect.DeepEqual(got, tt.markers) {
+				t.Errorf("Markers() = %v, want %v", got, tt.markers)
+			}
+		})
+	}
+
+	if len(footnotes.Legend) != 5 || footnotes.Legend["e"] != "Based on cross-country regression." {
+		t.Errorf("legend = %v, want markers a to e", footnotes.Legend)
+	}
+	if len(rows) != 3 {
+		t.Errorf("got %d rows, want 3: legend lines must not become rows", len(rows))
+	}
+}
@@ -0,0 +1,85 @@
+package src
+
+import (
+	"sort"
+	"strings"
+)
+
+// buildLanguageIndex lists every language with the countries that use it,
+// sorted by language name.
+func buildLanguageIndex(countries []Country) []LanguageEntry {
+	entries := make(map[string]*LanguageEntry)
+	for _, country := range countries {
+		for _, lang := range country.Languages {
+			entry, ok := entries[lang.Code]
+			if !ok {
+				entry = &LanguageEntry{Code: lang.Code, Name: lang.Name}
+				entries[lang.Code] = entry
+			}
+			entry.Countries = append(entry.Countries, newCountryRef(country))
+		}
+	}
+
+	index := make([]LanguageEntry, 0, len(entries))
+	for _, entry := range entries {
+		sortCountryRefs(entry.Countries)
+		index = append(index, *entry)
+	}
+	sort.Slice(index, func(i, j int) bool {
+		if index[i].Name != index[j].Name {
+			return index[i].Name < index[j].Name
+		}
+		return index[i].Code < index[j].Code
+	})
+	return index
+}
+
+// buildCurrencyIndex lists every currency with the countries that use it,
+// sorted by currency code.
+func buildCurrencyIndex(countries []Country) []CurrencyEntry {
+	entries := make(map[string]*CurrencyEntry)
+	for _, country := range countries {
+		for _, curr := range country.Currencies {
+			entry, ok := entries[curr.Code]
+			if !ok {
+				entry = &CurrencyEntry{Code: curr.Code, Name: curr.Name, Symbol: curr.Symbol}
+				entries[curr.Code] = entry
+			}
+			entry.Countries = append(entry.Countries, newCountryRef(country))
+		}
+	}
+
+	index := make([]CurrencyEntry, 0, len(entries))
+	for _, entry := range entries {
+		sortCountryRefs(entry.Countries)
+		index = append(index, *entry)
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].Code < index[j].Code })
+	return index
+}
+
+func newCountryRef(country Country) CountryRef {
+	return CountryRef{Code: country.Cca3, Name: country.Name, Flag: country.Flag}
+}
+
+func sortCountryRefs(refs []CountryRef) {
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+}
+
+func hasLanguage(country Country, code string) bool {
+	for _, lang := range country.Languages {
+		if strings.EqualFold(lang.Code, code) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCurrency(country Country, code string) bool {
+	for _, curr := range country.Currencies {
+		if strings.EqualFold(curr.Code, code) {
+			return true
+		}
+	}
+	return false
+}
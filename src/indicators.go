@@ -0,0 +1,325 @@
+package src
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IndicatorDescriptor tells the loader how to read one country-level CSV.
+// Rows are matched to countries by the ISO3 column when there is one,
+// otherwise by name like the HDI annex, with Aliases mapping names to cca3.
+type IndicatorDescriptor struct {
+	File       string            `json:"file"`
+	Source     string            `json:"source,omitempty"`
+	ISO3Column string            `json:"iso3_column,omitempty"`
+	NameColumn string            `json:"name_column,omitempty"`
+	Aliases    map[string]string `json:"aliases,omitempty"`
+	Values     []IndicatorColumn `json:"values"`
+}
+
+// IndicatorColumn maps a CSV column to an indicator key.
+type IndicatorColumn struct {
+	Key    string `json:"key"`
+	Column string `json:"column"`
+	Name   string `json:"name"`
+	Unit   string `json:"unit,omitempty"`
+}
+
+// Indicator is a loaded indicator as listed by /api/indicators.
+type Indicator struct {
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	Unit      string `json:"unit,omitempty"`
+	Source    string `json:"source,omitempty"`
+	File      string `json:"file"`
+	Countries int    `json:"countries"`
+}
+
+var indicatorKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// loadIndicators reads every descriptor (*.json) in dir and attaches the
+// values of its CSV to the countries. Broken descriptors are logged and
+// skipped; a missing directory simply means no indicators.
+func loadIndicators(dir string, countries []Country) []Indicator {
+	descriptors, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(descriptors) == 0 {
+		return nil
+	}
+	sort.Strings(descriptors)
+
+	var indicators []Indicator
+	seen := make(map[string]bool)
+	for _, path := range descriptors {
+		loaded, err := loadIndicatorFile(path, countries, seen)
+		if err != nil {
+			log.Printf("Warning: Skipping indicator descriptor %s: %v", path, err)
+			continue
+		}
+		indicators = append(indicators, loaded...)
+	}
+	if len(indicators) > 0 {
+		log.Printf("Loaded %d indicators from %s", len(indicators), dir)
+	}
+	return indicators
+}
+
+func loadIndicatorFile(path string, countries []Country, seen map[string]bool) ([]Indicator, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var descriptor IndicatorDescriptor
+	if err := json.Unmarshal(content, &descriptor); err != nil {
+		return nil, err
+	}
+	if err := descriptor.validate(seen); err != nil {
+		return nil, err
+	}
+
+	csvPath := descriptor.File
+	if !filepath.IsAbs(csvPath) {
+		csvPath = filepath.Join(filepath.Dir(path), csvPath)
+	}
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows, err := readIndicatorRows(file, descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", csvPath, err)
+	}
+
+	// Match rows to countries, by code directly or through name reconciliation
+	matches := make(map[string]int)
+	if descriptor.ISO3Column != "" {
+		byCode := make(map[string]int, len(countries))
+		for i, country := range countries {
+			byCode[country.Cca3] = i
+		}
+		for key := range rows {
+			if i, ok := byCode[strings.ToUpper(key)]; ok {
+				matches[key] = i
+			}
+		}
+	} else {
+		codes := make(map[string]string, len(rows))
+		for name := range rows {
+			codes[name] = ""
+		}
+		for _, match := range reconcileNames(countries, codes, descriptor.Aliases).Matches {
+			matches[match.HDIName] = match.index
+		}
+	}
+
+	indicators := make([]Indicator, len(descriptor.Values))
+	for v, column := range descriptor.Values {
+		indicators[v] = Indicator{
+			Key:    column.Key,
+			Name:   column.Name,
+			Unit:   column.Unit,
+			Source: descriptor.Source,
+			File:   filepath.Base(csvPath),
+		}
+		seen[column.Key] = true
+	}
+	for key, i := range matches {
+		for v, value := range rows[key] {
+			if math.IsNaN(value) {
+				continue
+			}
+			if countries[i].Indicators == nil {
+				countries[i].Indicators = make(map[string]float64)
+			}
+			countries[i].Indicators[descriptor.Values[v].Key] = value
+			indicators[v].Countries++
+		}
+	}
+	return indicators, nil
+}
+
+func (d IndicatorDescriptor) validate(seen map[string]bool) error {
+	if d.File == "" {
+		return errors.New("no file")
+	}
+	if d.ISO3Column == "" && d.NameColumn == "" {
+		return errors.New("one of iso3_column or name_column is required")
+	}
+	if len(d.Values) == 0 {
+		return errors.New("no values")
+	}
+	// seen only holds keys of descriptors that loaded, so a broken one does
+	// not reserve its keys; keys repeated within d are tracked separately
+	keys := make(map[string]bool, len(d.Values))
+	for _, column := range d.Values {
+		if !indicatorKeyPattern.MatchString(column.Key) {
+			return fmt.Errorf("invalid key %q, use lower case letters, digits and underscores", column.Key)
+		}
+		if seen[column.Key] || keys[column.Key] {
+			return fmt.Errorf("indicator %q is already defined", column.Key)
+		}
+		keys[column.Key] = true
+		if column.Column == "" {
+			return fmt.Errorf("indicator %q has no column", column.Key)
+		}
+	}
+	return nil
+}
+
+// readIndicatorRows returns the values of each row keyed by ISO3 code or
+// name, in descriptor order. Missing values are NaN. The header is the first
+// row containing the key column, so title rows above it are ignored.
+func readIndicatorRows(r io.Reader, descriptor IndicatorDescriptor) (map[string][]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	keyColumn := descriptor.ISO3Column
+	if keyColumn == "" {
+		keyColumn = descriptor.NameColumn
+	}
+
+	rows := make(map[string][]float64)
+	var key int
+	var columns []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(strings.TrimPrefix(record[i], "\ufeff"))
+		}
+
+		if columns == nil {
+			if key = indexOf(record, keyColumn); key < 0 {
+				continue
+			}
+			columns = make([]int, len(descriptor.Values))
+			for v, column := range descriptor.Values {
+				if columns[v] = indexOf(record, column.Column); columns[v] < 0 {
+					return nil, fmt.Errorf("column %q not found", column.Column)
+				}
+			}
+			continue
+		}
+
+		name := field(record, key)
+		if name == "" {
+			continue
+		}
+		values := make([]float64, len(columns))
+		for v, column := range columns {
+			value, ok := parseHDINumber(field(record, column))
+			if !ok {
+				value = math.NaN()
+			}
+			values[v] = value
+		}
+		rows[name] = values
+	}
+
+	if columns == nil {
+		return nil, fmt.Errorf("header with column %q not found", keyColumn)
+	}
+	return rows, nil
+}
+
+// Query parameters read by queryIndicators
+var indicatorParams = []string{"indicator", "min", "max", "sort", "order"}
+
+// queryIndicators applies the indicator parameters of /api/countries and the
+// home page:
+// indicator (keep countries with a value), min and max (bounds on that
+// value), sort (an indicator key, missing values last) and order (asc or
+// desc). The result is a new slice; countries are not modified.
+func queryIndicators(countries []Country, indicators []Indicator, query url.Values) ([]Country, error) {
+	known := func(key string) bool {
+		for _, indicator := range indicators {
+			if indicator.Key == key {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch query.Get("order") {
+	case "", "asc", "desc":
+	default:
+		return nil, fmt.Errorf("invalid order: %s", query.Get("order"))
+	}
+
+	result := append([]Country{}, countries...)
+	if key := query.Get("indicator"); key != "" {
+		if !known(key) {
+			return nil, fmt.Errorf("unknown indicator: %s", key)
+		}
+		lower, upper := math.Inf(-1), math.Inf(1)
+		for param, bound := range map[string]*float64{"min": &lower, "max": &upper} {
+			if value := query.Get(param); value != "" {
+				number, err := strconv.ParseFloat(value, 64)
+				if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+					return nil, fmt.Errorf("invalid %s: %s", param, value)
+				}
+				*bound = number
+			}
+		}
+
+		result = []Country{}
+		for _, country := range countries {
+			if value, ok := country.Indicators[key]; ok && value >= lower && value <= upper {
+				result = append(result, country)
+			}
+		}
+	} else if query.Get("min") != "" || query.Get("max") != "" {
+		return nil, errors.New("min and max require an indicator")
+	}
+
+	key := query.Get("sort")
+	if key == "" {
+		return result, nil
+	}
+	if !known(key) {
+		return nil, fmt.Errorf("unknown indicator: %s", key)
+	}
+	descending := query.Get("order") == "desc"
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, aok := result[i].Indicators[key]
+		b, bok := result[j].Indicators[key]
+		if aok != bok {
+			return aok
+		}
+		if descending {
+			return a > b
+		}
+		return a < b
+	})
+	return result, nil
+}
+
+// FormatIndicator returns the country's value of an indicator for display,
+// or "" when it has none.
+func (c Country) FormatIndicator(key string) string {
+	value, ok := c.Indicators[key]
+	if !ok {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
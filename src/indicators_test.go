@@ -0,0 +1,85 @@
+package src
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestIndicatorDescriptorValidateRejectsDuplicateKeys(t *testing.T) {
+	descriptor := IndicatorDescriptor{
+		File:       "indicators.csv",
+		ISO3Column: "iso3",
+		Values: []IndicatorColumn{
+			{Key: "gdp", Column: "GDP 2022"},
+			{Key: "gdp", Column: "GDP 2023"},
+		},
+	}
+	err := descriptor.validate(map[string]bool{})
+	if err == nil || !strings.Contains(err.Error(), `"gdp" is already defined`) {
+		t.Errorf("validate() error = %v, want duplicate key", err)
+	}
+
+	descriptor.Values[1].Key = "gdp_2023"
+	if err := descriptor.validate(map[string]bool{}); err != nil {
+		t.Errorf("validate() error = %v for distinct keys", err)
+	}
+	if err := descriptor.validate(map[string]bool{"gdp_2023": true}); err == nil {
+		t.Error("validate() accepted a key defined by another descriptor")
+	}
+}
+
+func TestQueryIndicators(t *testing.T) {
+	indicators := []Indicator{{Key: "gdp"}}
+	countries := []Country{
+		{Name: "A", Indicators: map[string]float64{"gdp": 30}},
+		{Name: "B"},
+		{Name: "C", Indicators: map[string]float64{"gdp": 10}},
+	}
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"", "A,B,C", false},
+		{"indicator=gdp", "A,C", false},
+		{"indicator=gdp&min=20", "A", false},
+		{"indicator=gdp&max=5", "", false},
+		{"sort=gdp", "C,A,B", false},
+		{"sort=gdp&order=desc", "A,C,B", false},
+		{"indicator=gdp&sort=gdp&order=desc", "A,C", false},
+		{"indicator=hdi", "", true},
+		{"sort=hdi", "", true},
+		{"min=1", "", true},
+		{"indicator=gdp&min=low", "", true},
+		{"indicator=gdp&min=NaN", "", true},
+		{"indicator=gdp&max=Inf", "", true},
+		{"indicator=gdp&min=-Inf", "", true},
+		{"sort=gdp&order=up", "", true},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := queryIndicators(countries, indicators, query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got == nil {
+			t.Errorf("%q: got nil, want an empty slice", tt.query)
+		}
+		var names []string
+		for _, country := range got {
+			names = append(names, country.Name)
+		}
+		if joined := strings.Join(names, ","); joined != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.query, joined, tt.want)
+		}
+	}
+
+	if got, err := queryIndicators(nil, indicators, url.Values{}); err != nil || got == nil {
+		t.Errorf("queryIndicators(nil) = %v, %v, want an empty slice", got, err)
+	}
+}
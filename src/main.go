@@ -3,33 +3,66 @@ package src
 import (
 	"log"
 	"net/http"
+	"os"
 )
 
 var (
-	allCountries []Country
-	favorites    Favorites
+	favorites     Favorites
+	dataRefresher *refresher
 )
 
-// Run starts the application
+// Run starts the application, or with the "snapshot" argument writes the
+// countries snapshot and exits
 func Run() {
+	if len(os.Args) > 1 && os.Args[1] == "snapshot" {
+		if err := writeSnapshot(); err != nil {
+			log.Fatal("Error writing countries snapshot:", err)
+		}
+		return
+	}
+
 	if err := loadFavorites(); err != nil {
 		log.Fatal("Error loading favorites:", err)
 	}
 
-	var err error
-	allCountries, err = fetchCountries()
+	provider, err := newCountryProvider(getEnv("COUNTRIES_SOURCE", sourceAuto))
 	if err != nil {
-		log.Fatal("Error fetching countries:", err)
+		log.Fatal("Error configuring country source:", err)
 	}
 
+	// Load the data in the background so the server answers right away; until
+	// the first load succeeds the pages are in their degraded state
+	dataRefresher = newRefresher(provider, parseRefreshInterval(getEnv("REFRESH_INTERVAL", "24h")))
+	go func() {
+		if _, err := dataRefresher.refresh(); err != nil {
+			log.Printf("Warning: Country data unavailable, running in degraded mode: %v", err)
+		}
+		dataRefresher.run()
+	}()
+
 	// Register all specific routes first
 	http.HandleFunc("/favorites", handleFavorites)
 	http.HandleFunc("/api/favorite", handleFavoriteAPI)
 	http.HandleFunc("/about", handleAbout)
 	http.HandleFunc("/error", handleError)
 	http.HandleFunc("/map", handleMap)
+	http.HandleFunc("/meeting", handleMeeting)
 	http.HandleFunc("/api/countries", handleCountriesAPI)
 	http.HandleFunc("/api/timezone-borders", handleTimezoneBorders)
+	http.HandleFunc("/api/data-quality", handleDataQualityAPI)
+	http.HandleFunc("/api/hdi-reconciliation", handleHDIReconciliationAPI)
+	http.HandleFunc("/api/hdi/history", handleHDIHistoryAPI)
+	http.HandleFunc("/api/hdi/footnotes", handleHDIFootnotesAPI)
+	http.HandleFunc("/api/indicators", handleIndicatorsAPI)
+	http.HandleFunc("/api/aggregates", handleAggregatesAPI)
+	http.HandleFunc("/api/meeting", handleMeetingAPI)
+	http.HandleFunc("/api/convert", handleConvertAPI)
+	http.HandleFunc("/api/languages", handleLanguagesAPI)
+	http.HandleFunc("/api/currencies", handleCurrenciesAPI)
+	http.HandleFunc("/api/neighbours", handleNeighboursAPI)
+	http.HandleFunc("/api/neighbours/path", handleNeighbourPathAPI)
+	http.HandleFunc("/api/neighbours/components", handleComponentsAPI)
+	http.HandleFunc("/api/admin/refresh", handleRefreshAPI)
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
 
 	// Register the catch-all handler last
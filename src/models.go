@@ -1,46 +1,315 @@
 package src
 
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// HDIData holds one country's row of the HDI annex table. Year is the year
+// of the values; PreviousRank is the rank in PreviousRankYear. Zero means the
+// annex has no value, except for GNIRankMinusHDIRank where 0 is a valid
+// difference.
 type HDIData struct {
-	HDIRank     int     `json:"hdi_rank"`
-	HDIValue    float64 `json:"hdi_value"`
-	Category    string  `json:"category"`
-	LifeExpect  float64 `json:"life_expectancy"`
-	SchoolYears float64 `json:"school_years"`
-	GNIPerCap   string  `json:"gni_per_capita"`
+	Name                string  `json:"name"`
+	ISO3                string  `json:"iso3,omitempty"`
+	HDIRank             int     `json:"hdi_rank"`
+	HDIValue            float64 `json:"hdi_value"`
+	Category            string  `json:"category"`
+	Year                int     `json:"year"`
+	LifeExpect          float64 `json:"life_expectancy"`
+	ExpectedSchooling   float64 `json:"expected_schooling"`
+	MeanSchooling       float64 `json:"mean_schooling"`
+	GNIPerCapita        float64 `json:"gni_per_capita"`
+	GNIRankMinusHDIRank int     `json:"gni_rank_minus_hdi_rank"`
+	PreviousRank        int     `json:"previous_rank"`
+	PreviousRankYear    int     `json:"previous_rank_year"`
+
+	// Footnote markers of the annex keyed by value, e.g. "mean_schooling":
+	// ["c"]; HDIFootnotes explains them
+	Notes map[string][]string `json:"notes,omitempty"`
+}
+
+// Markers lists every footnote marker used in the row, without duplicates.
+func (h HDIData) Markers() []string {
+	seen := make(map[string]bool)
+	var markers []string
+	for _, notes := range h.Notes {
+		for _, marker := range notes {
+			if !seen[marker] {
+				seen[marker] = true
+				markers = append(markers, marker)
+			}
+		}
+	}
+	// Annex order: a to z, then aa, ab, ...
+	sort.Slice(markers, func(i, j int) bool {
+		if len(markers[i]) != len(markers[j]) {
+			return len(markers[i]) < len(markers[j])
+		}
+		return markers[i] < markers[j]
+	})
+	return markers
+}
+
+// HDIFootnotes is the legend of the footnote markers of an annex.
+type HDIFootnotes struct {
+	Year   int               `json:"year"`
+	Legend map[string]string `json:"legend"`
+}
+
+// HDIPoint is one year of a country's HDI series. Change and AnnualChange
+// compare HDIValue with the point for PreviousYear and are zero on the first
+// point of the series.
+type HDIPoint struct {
+	Year              int                 `json:"year"`
+	HDIValue          float64             `json:"hdi_value"`
+	HDIRank           int                 `json:"hdi_rank"`
+	Category          string              `json:"category"`
+	LifeExpect        float64             `json:"life_expectancy"`
+	ExpectedSchooling float64             `json:"expected_schooling"`
+	MeanSchooling     float64             `json:"mean_schooling"`
+	GNIPerCapita      float64             `json:"gni_per_capita"`
+	Notes             map[string][]string `json:"notes,omitempty"`
+	PreviousYear      int                 `json:"previous_year,omitempty"`
+	Change            float64             `json:"change"`
+	AnnualChange      float64             `json:"annual_change"`
+}
+
+// RawCountry is a single record as returned by the restcountries API.
+type RawCountry struct {
+	Name struct {
+		Common     string `json:"common"`
+		Official   string `json:"official"`
+		NativeName map[string]struct {
+			Official string `json:"official"`
+			Common   string `json:"common"`
+		} `json:"nativeName"`
+	} `json:"name"`
+	Cca2        string   `json:"cca2"`
+	Cca3        string   `json:"cca3"`
+	Ccn3        string   `json:"ccn3"`
+	Capital     []string `json:"capital"`
+	CapitalInfo struct {
+		LatLng []float64 `json:"latlng"`
+	} `json:"capitalInfo"`
+	Region     string            `json:"region"`
+	Flag       string            `json:"flag"`
+	TimeZones  []string          `json:"timezones"`
+	Population int               `json:"population"`
+	Area       float64           `json:"area"`
+	Languages  map[string]string `json:"languages"`
+	Currencies map[string]struct {
+		Name   string `json:"name"`
+		Symbol string `json:"symbol"`
+	} `json:"currencies"`
+	IDD struct {
+		Root     string   `json:"root"`
+		Suffixes []string `json:"suffixes"`
+	} `json:"idd"`
+	Car struct {
+		Side string `json:"side"`
+	} `json:"car"`
+	Borders []string `json:"borders"`
+}
+
+type NativeName struct {
+	Language string `json:"language"`
+	Official string `json:"official"`
+	Common   string `json:"common"`
+}
+
+type Language struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type Currency struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+}
+
+// Neighbour is a country reached across a land border. TimeDifference is the
+// neighbour's main time zone relative to the country it borders.
+type Neighbour struct {
+	Code                  string `json:"code"`
+	Name                  string `json:"name"`
+	Flag                  string `json:"flag"`
+	TimeDifference        string `json:"timeDifference"`
+	TimeDifferenceMinutes int    `json:"timeDifferenceMinutes"`
+
+	loc *time.Location // the neighbour's local time, for the difference
 }
 
 type Country struct {
-	Name        string   `json:"name"`
-	TimeZone    string   `json:"-"`
-	Capital     string   `json:"capital"`
-	Region      string   `json:"region"`
-	Flag        string   `json:"flag"`
-	TimeZones   []string `json:"timezones"`
-	CurrentTime string   `json:"-"`
-	IsFavorite  bool     `json:"-"`
-	Population  string   `json:"population"`
-	Area        float64  `json:"area"`
-	Languages   []string `json:"languages"`
-	Currency    string   `json:"currency"`
-	CallingCode string   `json:"callingCode"`
-	DrivingSide string   `json:"drivingSide"`
-	Borders     []string `json:"borders"`
-	HDI         HDIData  `json:"hdi"`
+	Name          string       `json:"name"`
+	OfficialName  string       `json:"officialName"`
+	NativeNames   []NativeName `json:"nativeNames"`
+	Cca2          string       `json:"cca2"`
+	Cca3          string       `json:"cca3"`
+	Ccn3          string       `json:"ccn3"`
+	TimeZone      UTCOffset    `json:"-"`
+	Capital       string       `json:"capital"`
+	Capitals      []string     `json:"capitals"`
+	Region        string       `json:"region"`
+	Flag          string       `json:"flag"`
+	TimeZones     []UTCOffset  `json:"timezones"`
+	IANAZones     []string     `json:"ianaZones"`
+	IANAZone      string       `json:"ianaZone"`
+	ZoneSource    string       `json:"zoneSource"`
+	CapitalLatLng []float64    `json:"capitalLatLng,omitempty"`
+
+	// Local time at request time, see withLocalTimes. DayShift is the number
+	// of calendar days between CurrentDate and the UTC date of that instant.
+	CurrentTime   string     `json:"currentTime"`
+	CurrentDate   string     `json:"currentDate"`
+	DayShift      int        `json:"dayShift"`
+	CurrentOffset UTCOffset  `json:"currentOffset"`
+	ZoneTimes     []ZoneTime `json:"zoneTimes"`
+	TimeOfDay     string     `json:"timeOfDay"`
+	DayNight      string     `json:"dayNight"`
+
+	IsFavorite bool    `json:"-"`
+	Population int     `json:"population"`
+	Area       float64 `json:"area"`
+
+	// Derived from Population and Area after loading
+	PopulationDensity    float64 `json:"populationDensity"`
+	WorldPopulationShare float64 `json:"worldPopulationShare"`
+
+	Languages    []Language         `json:"languages"`
+	Currency     string             `json:"currency"`
+	Currencies   []Currency         `json:"currencies"`
+	CallingCode  string             `json:"callingCode"`
+	CallingCodes []string           `json:"callingCodes"`
+	DrivingSide  string             `json:"drivingSide"`
+	Borders      []string           `json:"borders"`
+	Neighbours   []Neighbour        `json:"neighbours"`
+	HDI          HDIData            `json:"hdi"`
+	Indicators   map[string]float64 `json:"indicators,omitempty"`
+
+	// Derived from HDI after loading; a zero rank means no HDI data
+	HDIRegionRank  int     `json:"hdiRegionRank"`
+	HDIRegionCount int     `json:"hdiRegionCount"`
+	HDIPercentile  float64 `json:"hdiPercentile"`
+
+	// Time zone strings from the source that are not valid UTC offsets
+	invalidTimeZones []string
+}
+
+// ZoneSourceLabel describes how IANAZone was chosen, for the cards.
+func (c Country) ZoneSourceLabel() string {
+	switch c.ZoneSource {
+	case zoneFromCapital:
+		return "Time zone of the capital"
+	case zoneFromNearestCity:
+		return "Time zone of the city nearest the capital"
+	case zoneFromOffset:
+		return "Time zone matching the main UTC offset"
+	}
+	return "Fixed UTC offset"
+}
+
+// ZoneTime is the local time in one zone of a country. Zone is empty for a
+// fixed UTC offset; Capital marks the zone of CurrentTime.
+type ZoneTime struct {
+	Zone     string    `json:"zone,omitempty"`
+	Offset   UTCOffset `json:"offset"`
+	Time     string    `json:"time"`
+	Date     string    `json:"date"`
+	DayShift int       `json:"dayShift"`
+	Capital  bool      `json:"capital"`
+}
+
+// Name returns the IANA zone, or the offset for a fixed zone.
+func (z ZoneTime) Name() string {
+	if z.Zone == "" {
+		return z.Offset.String()
+	}
+	return z.Zone
+}
+
+// CountryRef is a short reference to a country used in indexes.
+type CountryRef struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Flag string `json:"flag"`
+}
+
+type LanguageEntry struct {
+	Code      string       `json:"code"`
+	Name      string       `json:"name"`
+	Countries []CountryRef `json:"countries"`
+}
+
+type CurrencyEntry struct {
+	Code      string       `json:"code"`
+	Name      string       `json:"name"`
+	Symbol    string       `json:"symbol"`
+	Countries []CountryRef `json:"countries"`
 }
 
 type PageData struct {
-	Countries    []Country
-	Query        string
-	Regions      []string
-	TimeZones    []string
-	CurrentPage  int
-	TotalPages   int
-	ItemsPerPage int
-	Region       string
-	TimeZone     string
-	TimeRange    string
+	Countries     []Country
+	Query         string
+	Regions       []string
+	TimeZones     []UTCOffset
+	CurrentPage   int
+	TotalPages    int
+	ItemsPerPage  int
+	Region        string
+	TimeZone      string
+	TimeRange     string
+	TimeRangeZone string
+	At            string // the at parameter, empty for now
+	Instant       string
+	Languages     []LanguageEntry
+	Currencies    []CurrencyEntry
+	Language      string
+	Currency      string
+	FilterQuery   string
+
+	// HDI filters
+	HDICategory          string
+	HDICategories        []string
+	HDIMin               string
+	HDIMax               string
+	LifeMin              string
+	LifeMax              string
+	ExpectedSchoolingMin string
+	ExpectedSchoolingMax string
+	MeanSchoolingMin     string
+	MeanSchoolingMax     string
+
+	// Indicator filter and sort, see queryIndicators
+	Indicators   []Indicator
+	Indicator    string
+	IndicatorMin string
+	IndicatorMax string
+	Sort         string
+	Order        string
+
+	Aggregates   AggregateReport
+	HDIFootnotes HDIFootnotes
+	DataSource   SourceInfo
+
+	// Set while the server runs in degraded mode without country data
+	DataUnavailable bool
+	LastAttempt     time.Time
+	LoadError       string
 }
 
 type Favorites struct {
 	Countries []string `json:"countries"`
 }
+
+// CountrySnapshot is the on-disk copy of a restcountries response. Countries
+// holds the upstream JSON array unchanged, so a snapshot decodes exactly like
+// a live response. Version is bumped whenever the format changes.
+type CountrySnapshot struct {
+	Version   int             `json:"version"`
+	Source    string          `json:"source"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	Countries json.RawMessage `json:"countries"`
+}
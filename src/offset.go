@@ -0,0 +1,146 @@
+package src
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Range of offsets in use around the world
+const (
+	minUTCOffset UTCOffset = -12 * 60
+	maxUTCOffset UTCOffset = 14 * 60
+)
+
+// UTCOffset is a fixed offset from UTC in minutes east, as in the
+// "UTC+05:30" strings of restcountries. Offsets compare and subtract as
+// plain numbers; equivalent spellings such as "UTC" and "UTC+00:00" parse to
+// the same value.
+type UTCOffset int
+
+// ParseUTCOffset accepts "UTC", "UTC+05:30", "UTC-3", "UTC+0545" and the
+// same with a GMT prefix or none.
+func ParseUTCOffset(s string) (UTCOffset, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	for _, prefix := range []string{"UTC", "GMT"} {
+		value = strings.TrimPrefix(value, prefix)
+	}
+	if value == "" || value == "Z" {
+		return 0, nil
+	}
+
+	sign := 1
+	switch value[0] {
+	case '+':
+	case '-':
+		sign = -1
+	default:
+		return 0, fmt.Errorf("invalid UTC offset %q", s)
+	}
+	value = value[1:]
+
+	hoursPart, minutesPart, found := strings.Cut(value, ":")
+	if !found && len(value) > 2 {
+		hoursPart, minutesPart = value[:len(value)-2], value[len(value)-2:]
+	}
+	// Only digits: Atoi would also accept a second sign, as in "UTC+-5"
+	if !isDigits(hoursPart) || len(hoursPart) > 2 {
+		return 0, fmt.Errorf("invalid UTC offset %q", s)
+	}
+	hours, _ := strconv.Atoi(hoursPart)
+	minutes := 0
+	if found || minutesPart != "" {
+		if !isDigits(minutesPart) || len(minutesPart) != 2 {
+			return 0, fmt.Errorf("invalid UTC offset %q", s)
+		}
+		if minutes, _ = strconv.Atoi(minutesPart); minutes >= 60 {
+			return 0, fmt.Errorf("invalid UTC offset %q", s)
+		}
+	}
+
+	offset := UTCOffset(sign * (hours*60 + minutes))
+	if offset < minUTCOffset || offset > maxUTCOffset {
+		return 0, fmt.Errorf("UTC offset %q out of range", s)
+	}
+	return offset, nil
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// offsetAt returns the offset in effect at t in its location.
+func offsetAt(t time.Time) UTCOffset {
+	_, seconds := t.Zone()
+	return UTCOffset(seconds / 60)
+}
+
+// String renders the offset in its canonical form: "UTC", "UTC+02:00" or
+// "UTC-03:30".
+func (o UTCOffset) String() string {
+	if o == 0 {
+		return "UTC"
+	}
+	sign, minutes := '+', int(o)
+	if minutes < 0 {
+		sign, minutes = '-', -minutes
+	}
+	return fmt.Sprintf("UTC%c%02d:%02d", sign, minutes/60, minutes%60)
+}
+
+func (o UTCOffset) Minutes() int {
+	return int(o)
+}
+
+// Sub returns the time difference o - other, e.g. how far ahead o is.
+func (o UTCOffset) Sub(other UTCOffset) time.Duration {
+	return time.Duration(o-other) * time.Minute
+}
+
+// Location returns a fixed time zone for the offset.
+func (o UTCOffset) Location() *time.Location {
+	return time.FixedZone(o.String(), int(o)*60)
+}
+
+func (o UTCOffset) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *UTCOffset) UnmarshalText(text []byte) error {
+	offset, err := ParseUTCOffset(string(text))
+	if err != nil {
+		return err
+	}
+	*o = offset
+	return nil
+}
+
+// parseUTCOffsets parses a list of offsets, dropping duplicates, and returns
+// the strings it could not parse.
+func parseUTCOffsets(values []string) ([]UTCOffset, []string) {
+	var offsets []UTCOffset
+	var invalid []string
+	seen := make(map[UTCOffset]bool)
+	for _, value := range values {
+		offset, err := ParseUTCOffset(value)
+		if err != nil {
+			invalid = append(invalid, value)
+			continue
+		}
+		if !seen[offset] {
+			seen[offset] = true
+			offsets = append(offsets, offset)
+		}
+	}
+	return offsets, invalid
+}
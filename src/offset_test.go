@@ -0,0 +1,35 @@
+package src
+
+import "testing"
+
+func TestParseUTCOffset(t *testing.T) {
+	tests := []struct {
+		input string
+		want  UTCOffset
+	}{
+		{"UTC", 0},
+		{"Z", 0},
+		{"UTC+01:00", 60},
+		{"utc-05:30", -330},
+		{"GMT+9", 540},
+		{"+0545", 345},
+		{"UTC+14:00", 840},
+	}
+	for _, tt := range tests {
+		got, err := ParseUTCOffset(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseUTCOffset(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseUTCOffsetRejectsInvalid(t *testing.T) {
+	for _, input := range []string{
+		"UTC+-5", "UTC-+5", "UTC+05:-5", "UTC+05:+5", "UTC++05:00", "UTC+05:", "UTC+:30",
+		"UTC+1-5", "UTC+05:3", "UTC+05:60", "UTC+123:00", "UTC+٥", "UTC 5", "UTC+15:00",
+	} {
+		if got, err := ParseUTCOffset(input); err == nil {
+			t.Errorf("ParseUTCOffset(%q) = %v, want an error", input, got)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of the meeting planner
+const (
+	defaultWorkingHours   = "09:00-17:00"
+	defaultMeetingMinutes = 30
+	meetingStepMinutes    = 15
+	defaultMeetingSlots   = 10
+	maxMeetingSlots       = 100
+	maxMeetingDays        = 31
+	maxParticipants       = 20
+)
+
+// Countries whose weekend is not Saturday and Sunday, keyed by cca2
+var weekendDays = map[string][]time.Weekday{
+	"AF": {time.Thursday, time.Friday},
+	"BD": {time.Friday, time.Saturday},
+	"BH": {time.Friday, time.Saturday},
+	"DZ": {time.Friday, time.Saturday},
+	"EG": {time.Friday, time.Saturday},
+	"IL": {time.Friday, time.Saturday},
+	"IQ": {time.Friday, time.Saturday},
+	"IR": {time.Friday},
+	"JO": {time.Friday, time.Saturday},
+	"KW": {time.Friday, time.Saturday},
+	"LY": {time.Friday, time.Saturday},
+	"NP": {time.Saturday},
+	"OM": {time.Friday, time.Saturday},
+	"QA": {time.Friday, time.Saturday},
+	"SA": {time.Friday, time.Saturday},
+	"SD": {time.Friday, time.Saturday},
+	"SY": {time.Friday, time.Saturday},
+	"YE": {time.Friday, time.Saturday},
+}
+
+var defaultWeekend = []time.Weekday{time.Saturday, time.Sunday}
+
+// MeetingParticipant is a place taking part in a meeting, with its working
+// hours in local time.
+type MeetingParticipant struct {
+	Name    string   `json:"name"`
+	Zone    string   `json:"zone"`
+	Hours   string   `json:"hours"`
+	Weekend []string `json:"weekend"`
+
+	loc        *time.Location
+	start, end int // working hours in minutes after midnight
+	weekend    []time.Weekday
+}
+
+// LocalSlot is a meeting slot as seen by one participant.
+type LocalSlot struct {
+	Participant string    `json:"participant"`
+	Zone        string    `json:"zone"`
+	Offset      UTCOffset `json:"offset"`
+	Date        string    `json:"date"`
+	Weekday     string    `json:"weekday"`
+	Start       string    `json:"start"`
+	End         string    `json:"end"`
+}
+
+// MeetingSlot is a time when every participant is within working hours.
+// Margin is how far, in minutes, the slot stays from the nearest edge of any
+// participant's working hours; slots are ranked by it.
+type MeetingSlot struct {
+	Start  time.Time   `json:"start"`
+	End    time.Time   `json:"end"`
+	Margin int         `json:"margin"`
+	Local  []LocalSlot `json:"local"`
+}
+
+// MeetingPlan is the answer of the meeting planner. From and To are dates in
+// Zone, the zone of the first participant.
+type MeetingPlan struct {
+	From         string               `json:"from"`
+	To           string               `json:"to"`
+	Zone         string               `json:"zone"`
+	Duration     int                  `json:"duration"`
+	Participants []MeetingParticipant `json:"participants"`
+	Candidates   int                  `json:"candidates"`
+	Slots        []MeetingSlot        `json:"slots"`
+}
+
+// meetingRequest is a parsed planner query.
+type meetingRequest struct {
+	participants []MeetingParticipant
+	from, to     time.Time // dates in the first participant's zone, both included
+	duration     int
+	limit        int
+}
+
+// parseMeetingRequest reads the planner parameters: participant (repeated,
+// "country or zone" optionally followed by "@09:00-17:00") or participants
+// (the same, one per line), hours (the default working hours), from and to
+// (dates in the first participant's zone), duration (minutes) and limit
+// (number of slots).
+func parseMeetingRequest(countries []Country, values url.Values, today time.Time) (meetingRequest, error) {
+	request := meetingRequest{duration: defaultMeetingMinutes, limit: defaultMeetingSlots}
+
+	hours := values.Get("hours")
+	if hours == "" {
+		hours = defaultWorkingHours
+	}
+	if _, _, err := parseWorkingHours(hours); err != nil {
+		return request, err
+	}
+
+	specs := values["participant"]
+	for _, line := range strings.Split(values.Get("participants"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			specs = append(specs, line)
+		}
+	}
+	if len(specs) == 0 {
+		return request, errors.New("at least one participant is required")
+	}
+	if len(specs) > maxParticipants {
+		return request, fmt.Errorf("at most %d participants", maxParticipants)
+	}
+	for _, spec := range specs {
+		participant, err := newMeetingParticipant(countries, spec, hours)
+		if err != nil {
+			return request, err
+		}
+		request.participants = append(request.participants, participant)
+	}
+
+	var err error
+	today = today.In(request.participants[0].loc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	if request.from, err = parseMeetingDate(values.Get("from"), today); err != nil {
+		return request, err
+	}
+	if request.to, err = parseMeetingDate(values.Get("to"), request.from.AddDate(0, 0, 6)); err != nil {
+		return request, err
+	}
+	if request.to.Before(request.from) {
+		return request, errors.New("to is before from")
+	}
+	if request.to.Sub(request.from) >= maxMeetingDays*24*time.Hour {
+		return request, fmt.Errorf("at most %d days can be searched", maxMeetingDays)
+	}
+
+	if value := values.Get("duration"); value != "" {
+		if request.duration, err = strconv.Atoi(value); err != nil || request.duration <= 0 || request.duration > 24*60 {
+			return request, fmt.Errorf("invalid duration: %s", value)
+		}
+	}
+	if value := values.Get("limit"); value != "" {
+		if request.limit, err = strconv.Atoi(value); err != nil || request.limit <= 0 || request.limit > maxMeetingSlots {
+			return request, fmt.Errorf("invalid limit: %s, use 1 to %d", value, maxMeetingSlots)
+		}
+	}
+	return request, nil
+}
+
+// newMeetingParticipant resolves "Switzerland", "CH@08:00-16:00",
+// "America/Chicago" or "UTC+05:30@10:00-18:00". Countries use the zone of
+// their capital and their own weekend; zones and offsets use Saturday and
+// Sunday.
+func newMeetingParticipant(countries []Country, spec, defaultHours string) (MeetingParticipant, error) {
+	key, hours, found := strings.Cut(spec, "@")
+	key = strings.TrimSpace(key)
+	if !found {
+		hours = defaultHours
+	}
+
+	participant := MeetingParticipant{Name: key, Hours: strings.TrimSpace(hours), weekend: defaultWeekend}
+	var err error
+	if participant.start, participant.end, err = parseWorkingHours(participant.Hours); err != nil {
+		return participant, fmt.Errorf("%s: %w", key, err)
+	}
+
+	place, err := resolvePlace(countries, key)
+	if err != nil {
+		return participant, err
+	}
+	participant.Name, participant.Zone, participant.loc = place.name, place.zone, place.loc
+	if days, ok := weekendDays[place.cca2]; ok {
+		participant.weekend = days
+	}
+
+	for _, day := range participant.weekend {
+		participant.Weekend = append(participant.Weekend, day.String())
+	}
+	return participant, nil
+}
+
+// parseWorkingHours reads "09:00-17:00" into minutes after midnight. Working
+// hours cannot cross midnight.
+func parseWorkingHours(hours string) (int, int, error) {
+	startText, endText, found := strings.Cut(hours, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid working hours %q, use 09:00-17:00", hours)
+	}
+	start, startErr := parseClock(startText)
+	end, endErr := parseClock(endText)
+	if startErr != nil || endErr != nil || end <= start {
+		return 0, 0, fmt.Errorf("invalid working hours %q, use 09:00-17:00", hours)
+	}
+	return start, end, nil
+}
+
+// parseClock reads "9:30" or "09:30" into minutes after midnight; "24:00" is
+// the end of the day.
+func parseClock(clock string) (int, error) {
+	hoursText, minutesText, _ := strings.Cut(strings.TrimSpace(clock), ":")
+	hours, err := strconv.Atoi(hoursText)
+	if err != nil {
+		return 0, err
+	}
+	minutes := 0
+	if minutesText != "" {
+		if minutes, err = strconv.Atoi(minutesText); err != nil {
+			return 0, err
+		}
+	}
+	total := hours*60 + minutes
+	if hours < 0 || minutes < 0 || minutes >= 60 || total > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	return total, nil
+}
+
+func parseMeetingDate(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", value)
+	}
+	return date, nil
+}
+
+// planMeeting tries every slot of the requested length starting on a quarter
+// hour between the start of from and the end of to in the first
+// participant's zone, keeps those inside every participant's working hours
+// on a local working day, and returns the best by margin, earliest first on
+// ties. Local times come from the IANA zones, so daylight saving changes
+// within the range are taken into account.
+func planMeeting(request meetingRequest) MeetingPlan {
+	loc := request.participants[0].loc
+	plan := MeetingPlan{
+		From:         request.from.Format("2006-01-02"),
+		To:           request.to.Format("2006-01-02"),
+		Zone:         request.participants[0].Zone,
+		Duration:     request.duration,
+		Participants: request.participants,
+		Slots:        []MeetingSlot{},
+	}
+
+	length := time.Duration(request.duration) * time.Minute
+	begin := time.Date(request.from.Year(), request.from.Month(), request.from.Day(), 0, 0, 0, 0, loc).UTC()
+	end := time.Date(request.to.Year(), request.to.Month(), request.to.Day()+1, 0, 0, 0, 0, loc).UTC()
+	for start := begin; start.Add(length).Compare(end) <= 0; start = start.Add(meetingStepMinutes * time.Minute) {
+		slot := MeetingSlot{Start: start, End: start.Add(length), Margin: 24 * 60}
+		for _, participant := range request.participants {
+			local, margin, ok := participant.fits(slot.Start, slot.End)
+			if !ok {
+				slot.Local = nil
+				break
+			}
+			slot.Local = append(slot.Local, local)
+			slot.Margin = min(slot.Margin, margin)
+		}
+		if slot.Local != nil {
+			plan.Slots = append(plan.Slots, slot)
+		}
+	}
+
+	plan.Candidates = len(plan.Slots)
+	sort.SliceStable(plan.Slots, func(i, j int) bool { return plan.Slots[i].Margin > plan.Slots[j].Margin })
+	if len(plan.Slots) > request.limit {
+		plan.Slots = plan.Slots[:request.limit]
+	}
+	return plan
+}
+
+// fits reports whether a slot lies within the participant's working hours on
+// a working day, with the slot in local time and its margin in minutes.
+func (p MeetingParticipant) fits(start, end time.Time) (LocalSlot, int, bool) {
+	localStart, localEnd := start.In(p.loc), end.In(p.loc)
+	local := LocalSlot{
+		Participant: p.Name,
+		Zone:        p.Zone,
+		Offset:      offsetAt(localStart),
+		Date:        localStart.Format("2006-01-02"),
+		Weekday:     localStart.Weekday().String(),
+		Start:       localStart.Format("15:04"),
+		End:         localEnd.Format("15:04"),
+	}
+
+	for _, day := range p.weekend {
+		if localStart.Weekday() == day {
+			return local, 0, false
+		}
+	}
+	startMinutes := localStart.Hour()*60 + localStart.Minute()
+	endMinutes := localEnd.Hour()*60 + localEnd.Minute()
+	if localEnd.YearDay() != localStart.YearDay() {
+		// Ending exactly at midnight is the only way to finish on the next day
+		if endMinutes != 0 {
+			return local, 0, false
+		}
+		endMinutes = 24 * 60
+	}
+	if startMinutes < p.start || endMinutes > p.end {
+		return local, 0, false
+	}
+	return local, min(startMinutes-p.start, p.end-endMinutes), true
+}
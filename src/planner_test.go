@@ -0,0 +1,99 @@
+package src
+
+import (
+	"testing"
+	"time"
+)
+
+func testParticipant(t *testing.T, zone string, weekend ...time.Weekday) MeetingParticipant {
+	t.Helper()
+	loc, err := loadLocation(zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weekend == nil {
+		weekend = defaultWeekend
+	}
+	return MeetingParticipant{Name: zone, Zone: zone, loc: loc, start: 9 * 60, end: 17 * 60, weekend: weekend}
+}
+
+func TestMeetingParticipantFits(t *testing.T) {
+	newYork := testParticipant(t, "America/New_York")
+	riyadh := testParticipant(t, "Asia/Riyadh", time.Friday, time.Saturday)
+	lateShift := testParticipant(t, "UTC")
+	lateShift.start, lateShift.end = 16*60, 24*60
+
+	utc := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name        string
+		participant MeetingParticipant
+		start       time.Time
+		minutes     int
+		wantOK      bool
+		wantStart   string
+		wantMargin  int
+		wantOffset  UTCOffset
+	}{
+		// Clocks in New York go forward on Sunday 9 March 2025
+		{"before the change, opening", newYork, utc(7, 14, 0), 30, true, "09:00", 0, -300},
+		{"before the change, too early", newYork, utc(7, 13, 0), 30, false, "08:00", 0, -300},
+		{"change day is a Sunday", newYork, utc(9, 15, 0), 30, false, "11:00", 0, -240},
+		{"after the change, same UTC time", newYork, utc(10, 14, 0), 30, true, "10:00", 60, -240},
+		{"after the change, opening", newYork, utc(10, 13, 0), 30, true, "09:00", 0, -240},
+		{"after the change, past closing", newYork, utc(10, 20, 45), 30, false, "16:45", 0, -240},
+		{"Friday is weekend", riyadh, utc(14, 7, 0), 60, false, "10:00", 0, 180},
+		{"Saturday is weekend", riyadh, utc(15, 7, 0), 60, false, "10:00", 0, 180},
+		{"Sunday is a working day", riyadh, utc(16, 7, 0), 60, true, "10:00", 60, 180},
+		{"Thursday is a working day", riyadh, utc(13, 13, 0), 60, true, "16:00", 0, 180},
+		{"ends at midnight", lateShift, utc(12, 23, 30), 30, true, "23:30", 0, 0},
+		{"crosses midnight", lateShift, utc(12, 23, 45), 30, false, "23:45", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end := tt.start.Add(time.Duration(tt.minutes) * time.Minute)
+			local, margin, ok := tt.participant.fits(tt.start, end)
+			if ok != tt.wantOK {
+				t.Errorf("fits() ok = %v, want %v (local %s %s)", ok, tt.wantOK, local.Weekday, local.Start)
+			}
+			if local.Start != tt.wantStart || local.Offset != tt.wantOffset {
+				t.Errorf("local start = %s %s, want %s %s", local.Start, local.Offset, tt.wantStart, tt.wantOffset)
+			}
+			if ok && margin != tt.wantMargin {
+				t.Errorf("margin = %d, want %d", margin, tt.wantMargin)
+			}
+		})
+	}
+}
+
+func TestPlanMeetingUsesQuarterHoursOfTheFirstZone(t *testing.T) {
+	kathmandu := testParticipant(t, "Asia/Kathmandu")
+	request := meetingRequest{
+		participants: []MeetingParticipant{kathmandu},
+		from:         time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC),
+		to:           time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC),
+		duration:     30,
+		limit:        maxMeetingSlots,
+	}
+
+	plan := planMeeting(request)
+	if plan.Zone != "Asia/Kathmandu" {
+		t.Errorf("zone = %s, want Asia/Kathmandu", plan.Zone)
+	}
+	// 09:00 to 17:00 in 15 minute steps, all on Monday 10 March local time
+	if plan.Candidates != 31 {
+		t.Errorf("got %d candidates, want 31", plan.Candidates)
+	}
+	opening := time.Date(2025, time.March, 10, 3, 15, 0, 0, time.UTC) // 09:00 at UTC+05:45
+	found := false
+	for _, slot := range plan.Slots {
+		if local := slot.Local[0]; local.Date != "2025-03-10" || slot.Start.Minute()%15 != 0 {
+			t.Errorf("slot at %s %s, want a quarter hour on 2025-03-10", local.Date, local.Start)
+		}
+		found = found || slot.Start.Equal(opening)
+	}
+	if !found {
+		t.Errorf("no slot at the opening of the working day, %s", opening)
+	}
+}
@@ -0,0 +1,312 @@
+package src
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// CountryProvider is a source of raw country records. Providers can be
+// composed, e.g. a live API backed by a local snapshot.
+type CountryProvider interface {
+	Name() string
+	Countries() ([]RawCountry, SourceInfo, error)
+}
+
+// SourceInfo describes where a set of countries came from and how old it is.
+type SourceInfo struct {
+	Name      string    `json:"name"`
+	FetchedAt time.Time `json:"fetched_at"`
+	FromCache bool      `json:"from_cache"`
+}
+
+// Age returns how long ago the data was fetched from its origin.
+func (s SourceInfo) Age() time.Duration {
+	if s.FetchedAt.IsZero() {
+		return 0
+	}
+	return time.Since(s.FetchedAt)
+}
+
+// newCountryProvider builds the provider selected by COUNTRIES_SOURCE. The
+// snapshot file is COUNTRIES_SNAPSHOT, countries-snapshot.json by default.
+func newCountryProvider(source string) (CountryProvider, error) {
+	snapshotFile := getEnv("COUNTRIES_SNAPSHOT", countriesSnapshotFile)
+	switch source {
+	case sourceAPI:
+		return newRESTCountriesProvider(countriesAPIURL), nil
+	case sourceSnapshot:
+		return newFileProvider(snapshotFile), nil
+	case sourceAuto:
+		api := newRESTCountriesProvider(countriesAPIURL)
+		api.snapshotPath = snapshotFile
+		return newFallbackProvider(api, newFileProvider(snapshotFile)), nil
+	default:
+		return nil, fmt.Errorf("unknown countries source %q", source)
+	}
+}
+
+// writeSnapshot fetches the countries from the API and writes them to the
+// snapshot file, replacing any existing snapshot.
+func writeSnapshot() error {
+	path := getEnv("COUNTRIES_SNAPSHOT", countriesSnapshotFile)
+	body, info, err := newRESTCountriesProvider(countriesAPIURL).fetch()
+	if err != nil {
+		return err
+	}
+	if info.FromCache {
+		log.Printf("Warning: The API could not be reached, writing the cached response")
+	}
+	if _, err := decodeCountries(body); err != nil {
+		return fmt.Errorf("restcountries returned invalid JSON: %w", err)
+	}
+	if err := saveCountriesSnapshot(path, body); err != nil {
+		return err
+	}
+	log.Printf("Wrote countries snapshot to %s", path)
+	return nil
+}
+
+func decodeCountries(body []byte) ([]RawCountry, error) {
+	var rawCountries []RawCountry
+	if err := json.Unmarshal(body, &rawCountries); err != nil {
+		return nil, err
+	}
+	return rawCountries, nil
+}
+
+// restCountriesProvider fetches countries from the restcountries HTTP API.
+// Responses are kept in an on-disk cache and revalidated with ETag and
+// Last-Modified; the cached body is used on 304 or when the API is
+// unreachable. When snapshotPath is set, a successful response is also
+// written there if no snapshot exists yet or COUNTRIES_SNAPSHOT_UPDATE is set.
+type restCountriesProvider struct {
+	url          string
+	client       *http.Client
+	attempts     int
+	backoff      time.Duration
+	cache        *httpCache
+	snapshotPath string
+}
+
+func newRESTCountriesProvider(url string) *restCountriesProvider {
+	return &restCountriesProvider{
+		url:      url,
+		client:   &http.Client{Timeout: upstreamTimeout},
+		attempts: upstreamAttempts,
+		backoff:  upstreamBackoff,
+		cache:    newHTTPCache(getEnv("CACHE_DIR", defaultCacheDir)),
+	}
+}
+
+func (p *restCountriesProvider) Name() string {
+	return "restcountries API"
+}
+
+func (p *restCountriesProvider) Countries() ([]RawCountry, SourceInfo, error) {
+	body, info, err := p.fetch()
+	if err != nil {
+		return nil, info, err
+	}
+
+	rawCountries, err := decodeCountries(body)
+	if err != nil {
+		return nil, info, fmt.Errorf("restcountries returned invalid JSON: %w", err)
+	}
+
+	if p.snapshotPath != "" && !info.FromCache {
+		_, statErr := os.Stat(p.snapshotPath)
+		if os.IsNotExist(statErr) || os.Getenv("COUNTRIES_SNAPSHOT_UPDATE") != "" {
+			if err := saveCountriesSnapshot(p.snapshotPath, body); err != nil {
+				log.Printf("Warning: Could not write countries snapshot: %v", err)
+			}
+		}
+	}
+
+	return rawCountries, info, nil
+}
+
+func (p *restCountriesProvider) fetch() ([]byte, SourceInfo, error) {
+	info := SourceInfo{Name: p.Name()}
+
+	var entry httpCacheEntry
+	var cached []byte
+	if p.cache != nil {
+		var err error
+		entry, cached, err = p.cache.load(p.url)
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: Ignoring unreadable HTTP cache entry: %v", err)
+		}
+	}
+
+	// useCache returns the cached body in place of a failed request
+	useCache := func(reason error) ([]byte, SourceInfo, error) {
+		if cached == nil {
+			return nil, info, reason
+		}
+		log.Printf("Warning: Using cached countries from %s: %v", entry.FetchedAt.Format(time.RFC3339), reason)
+		info.FetchedAt = entry.FetchedAt
+		info.FromCache = true
+		return cached, info, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, info, err
+	}
+	if cached != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := p.do(req)
+	if err != nil {
+		return useCache(err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		entry.ValidatedAt = time.Now().UTC()
+		if err := p.cache.storeEntry(p.url, entry); err != nil {
+			log.Printf("Warning: Could not update HTTP cache entry: %v", err)
+		}
+		info.FetchedAt = entry.FetchedAt
+		info.FromCache = true
+		return cached, info, nil
+	case resp.StatusCode != http.StatusOK:
+		return useCache(fmt.Errorf("restcountries returned %s", resp.Status))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return useCache(err)
+	}
+	if !json.Valid(body) {
+		return useCache(errors.New("restcountries returned invalid JSON"))
+	}
+
+	info.FetchedAt = time.Now().UTC()
+	if p.cache != nil {
+		entry = httpCacheEntry{
+			URL:          p.url,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			FetchedAt:    info.FetchedAt,
+			ValidatedAt:  info.FetchedAt,
+		}
+		if err := p.cache.store(p.url, entry, body); err != nil {
+			log.Printf("Warning: Could not write HTTP cache: %v", err)
+		}
+	}
+	return body, info, nil
+}
+
+// do sends the request, retrying network errors, 429 and 5xx responses with
+// exponential backoff.
+func (p *restCountriesProvider) do(req *http.Request) (*http.Response, error) {
+	delay := p.backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := p.client.Do(req)
+		if err == nil && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return resp, nil
+		}
+		if attempt >= p.attempts {
+			return resp, err
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("restcountries returned %s", resp.Status)
+		}
+		log.Printf("Warning: restcountries request failed (attempt %d of %d), retrying in %s: %v", attempt, p.attempts, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// fileProvider reads countries from a snapshot file written by
+// saveCountriesSnapshot.
+type fileProvider struct {
+	path string
+}
+
+func newFileProvider(path string) *fileProvider {
+	return &fileProvider{path: path}
+}
+
+func (p *fileProvider) Name() string {
+	return "snapshot " + p.path
+}
+
+func (p *fileProvider) Countries() ([]RawCountry, SourceInfo, error) {
+	info := SourceInfo{Name: p.Name()}
+	snapshot, err := loadCountriesSnapshot(p.path)
+	if err != nil {
+		return nil, info, err
+	}
+	info.FetchedAt = snapshot.FetchedAt
+	rawCountries, err := decodeCountries(snapshot.Countries)
+	return rawCountries, info, err
+}
+
+// memoryProvider serves a fixed list of countries, for fixtures and tests.
+type memoryProvider struct {
+	countries []RawCountry
+}
+
+func newMemoryProvider(countries ...RawCountry) *memoryProvider {
+	return &memoryProvider{countries: countries}
+}
+
+func (p *memoryProvider) Name() string {
+	return "in-memory fixture"
+}
+
+func (p *memoryProvider) Countries() ([]RawCountry, SourceInfo, error) {
+	info := SourceInfo{Name: p.Name(), FetchedAt: time.Now().UTC()}
+	if len(p.countries) == 0 {
+		return nil, info, errors.New("no countries in fixture")
+	}
+	return append([]RawCountry(nil), p.countries...), info, nil
+}
+
+// fallbackProvider tries each provider in order and returns the first
+// successful result.
+type fallbackProvider struct {
+	providers []CountryProvider
+}
+
+func newFallbackProvider(primary CountryProvider, fallbacks ...CountryProvider) *fallbackProvider {
+	return &fallbackProvider{providers: append([]CountryProvider{primary}, fallbacks...)}
+}
+
+func (p *fallbackProvider) Name() string {
+	names := make([]string, len(p.providers))
+	for i, provider := range p.providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, " -> ")
+}
+
+func (p *fallbackProvider) Countries() ([]RawCountry, SourceInfo, error) {
+	var errs []error
+	for _, provider := range p.providers {
+		rawCountries, info, err := provider.Countries()
+		if err == nil {
+			return rawCountries, info, nil
+		}
+		log.Printf("Warning: Could not load countries from %s: %v", provider.Name(), err)
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+	return nil, SourceInfo{Name: p.Name()}, errors.Join(errs...)
+}
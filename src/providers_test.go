@@ -0,0 +1,98 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	testETag         = `"v1"`
+	testLastModified = "Thu, 13 Mar 2025 10:00:00 GMT"
+	testCountries    = `[{"name":{"common":"Iceland"},"cca2":"IS","cca3":"ISL"}]`
+)
+
+func newTestRESTProvider(t *testing.T, url string) *restCountriesProvider {
+	t.Helper()
+	return &restCountriesProvider{
+		url:      url,
+		client:   http.DefaultClient,
+		attempts: 1,
+		cache:    newHTTPCache(t.TempDir()),
+	}
+}
+
+func TestRESTCountriesProviderRevalidatesCache(t *testing.T) {
+	responses := []int{http.StatusOK, http.StatusNotModified, http.StatusInternalServerError}
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := responses[len(requests)]
+		requests = append(requests, r)
+		if status == http.StatusOK {
+			w.Header().Set("ETag", testETag)
+			w.Header().Set("Last-Modified", testLastModified)
+			w.Write([]byte(testCountries))
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	provider := newTestRESTProvider(t, server.URL)
+	for i, wantFromCache := range []bool{false, true, true} {
+		rawCountries, info, err := provider.Countries()
+		if err != nil {
+			t.Fatalf("request %d: Countries() error = %v", i+1, err)
+		}
+		if len(rawCountries) != 1 || rawCountries[0].Cca3 != "ISL" {
+			t.Errorf("request %d: got %+v, want Iceland", i+1, rawCountries)
+		}
+		if info.FromCache != wantFromCache {
+			t.Errorf("request %d: FromCache = %v, want %v", i+1, info.FromCache, wantFromCache)
+		}
+	}
+
+	if len(requests) != len(responses) {
+		t.Fatalf("server got %d requests, want %d", len(requests), len(responses))
+	}
+	if got := requests[0].Header.Get("If-None-Match"); got != "" {
+		t.Errorf("first request sent If-None-Match %q", got)
+	}
+	if got := requests[0].Header.Get("If-Modified-Since"); got != "" {
+		t.Errorf("first request sent If-Modified-Since %q", got)
+	}
+	for _, r := range requests[1:] {
+		if got := r.Header.Get("If-None-Match"); got != testETag {
+			t.Errorf("If-None-Match = %q, want %q", got, testETag)
+		}
+		if got := r.Header.Get("If-Modified-Since"); got != testLastModified {
+			t.Errorf("If-Modified-Since = %q, want %q", got, testLastModified)
+		}
+	}
+}
+
+func TestRESTCountriesProviderFailsWithoutCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if _, _, err := newTestRESTProvider(t, server.URL).Countries(); err == nil {
+		t.Error("Countries() succeeded on a 500 with an empty cache")
+	}
+}
+
+func TestNewCountryProviderReadsSnapshotFromEnvironment(t *testing.T) {
+	t.Setenv("COUNTRIES_SNAPSHOT", "testdata/countries-snapshot.json")
+	provider, err := newCountryProvider(sourceSnapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawCountries, _, err := provider.Countries()
+	if err != nil {
+		t.Fatalf("Countries() error = %v", err)
+	}
+	if len(rawCountries) != 5 {
+		t.Errorf("got %d countries, want the 5 of the test snapshot", len(rawCountries))
+	}
+}
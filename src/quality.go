@@ -0,0 +1,135 @@
+package src
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of problems recorded by checkDataQuality
+const (
+	issueMissingCapital  = "missing_capital"
+	issueMissingTimeZone = "missing_timezone"
+	issueUnknownTimeZone = "unknown_timezone"
+	issueMissingIANAZone = "missing_iana_zone"
+	issueMissingRegion   = "missing_region"
+	issueMissingCode     = "missing_code"
+	issueMissingHDI      = "missing_hdi"
+	issueUnmatchedHDI    = "unmatched_hdi_row"
+	issueDuplicateName   = "duplicate_name"
+)
+
+type QualityIssue struct {
+	Kind    string `json:"kind"`
+	Country string `json:"country"`
+	Code    string `json:"code,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// QualityReport lists every anomaly found in a freshly loaded dataset.
+type QualityReport struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Countries   int            `json:"countries"`
+	IssueCount  int            `json:"issue_count"`
+	Counts      map[string]int `json:"counts"`
+	Issues      []QualityIssue `json:"issues"`
+}
+
+func checkDataQuality(countries []Country, reconciliation ReconciliationReport) QualityReport {
+	var issues []QualityIssue
+	add := func(kind string, country Country, detail string) {
+		issues = append(issues, QualityIssue{Kind: kind, Country: country.Name, Code: country.Cca3, Detail: detail})
+	}
+
+	names := make(map[string]int, len(countries))
+	for _, country := range countries {
+		names[country.Name]++
+
+		if country.Cca3 == "" {
+			add(issueMissingCode, country, "no cca3 code")
+		}
+		if country.Capital == "" {
+			add(issueMissingCapital, country, "")
+		}
+		if country.Region == "" {
+			add(issueMissingRegion, country, "")
+		}
+		if len(country.TimeZones) == 0 && len(country.invalidTimeZones) == 0 {
+			add(issueMissingTimeZone, country, "")
+		}
+		for _, tz := range country.invalidTimeZones {
+			add(issueUnknownTimeZone, country, tz)
+		}
+		if country.IANAZone == "" {
+			add(issueMissingIANAZone, country, "local time uses fixed offset "+country.TimeZone.String())
+		}
+	}
+
+	for _, ref := range reconciliation.UnmatchedCountries {
+		issues = append(issues, QualityIssue{Kind: issueMissingHDI, Country: ref.Name, Code: ref.Code})
+	}
+
+	for name, count := range names {
+		if count > 1 {
+			issues = append(issues, QualityIssue{Kind: issueDuplicateName, Country: name, Detail: fmt.Sprintf("%d countries share this name", count)})
+		}
+	}
+
+	for _, hdiName := range reconciliation.UnmatchedRows {
+		issues = append(issues, QualityIssue{Kind: issueUnmatchedHDI, Country: hdiName, Detail: "HDI row matched no country"})
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Kind != issues[j].Kind {
+			return issues[i].Kind < issues[j].Kind
+		}
+		return issues[i].Country < issues[j].Country
+	})
+
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.Kind]++
+	}
+
+	return QualityReport{
+		GeneratedAt: time.Now().UTC(),
+		Countries:   len(countries),
+		IssueCount:  len(issues),
+		Counts:      counts,
+		Issues:      issues,
+	}
+}
+
+// summary is the one-line overview logged after every load.
+func (q QualityReport) summary() string {
+	if q.IssueCount == 0 {
+		return fmt.Sprintf("Data quality: %d countries, no issues", q.Countries)
+	}
+
+	kinds := make([]string, 0, len(q.Counts))
+	for kind := range q.Counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		parts[i] = fmt.Sprintf("%s=%d", kind, q.Counts[kind])
+	}
+	return fmt.Sprintf("Data quality: %d countries, %d issues (%s)", q.Countries, q.IssueCount, strings.Join(parts, ", "))
+}
+
+// filter returns a copy of the report restricted to one kind of issue.
+func (q QualityReport) filter(kind string) QualityReport {
+	filtered := q
+	filtered.Issues = nil
+	for _, issue := range q.Issues {
+		if issue.Kind == kind {
+			filtered.Issues = append(filtered.Issues, issue)
+		}
+	}
+	filtered.IssueCount = len(filtered.Issues)
+	filtered.Counts = map[string]int{kind: filtered.IssueCount}
+	return filtered
+}
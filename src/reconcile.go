@@ -0,0 +1,214 @@
+package src
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Ways an HDI row can be matched to a country, in the order they are tried
+const (
+	matchISO3  = "iso3"
+	matchAlias = "alias"
+	matchName  = "name"
+	matchFuzzy = "fuzzy"
+)
+
+type HDIMatch struct {
+	HDIName string `json:"hdi_name"`
+	Code    string `json:"code"`
+	Country string `json:"country"`
+	Method  string `json:"method"`
+
+	index int
+}
+
+// ReconciliationReport records how every HDI row was attached to a country
+// and what was left over on either side.
+type ReconciliationReport struct {
+	Matches            []HDIMatch   `json:"matches"`
+	UnmatchedRows      []string     `json:"unmatched_rows"`
+	UnmatchedCountries []CountryRef `json:"unmatched_countries"`
+}
+
+// reconcileHDI matches HDI rows to countries: by ISO3 code when the annex
+// has one, then through the alias table (annex name -> cca3), then by
+// normalised name and finally by a close fuzzy match. A country is matched to
+// at most one row.
+func reconcileHDI(countries []Country, rows map[string]HDIData, aliases map[string]string) ReconciliationReport {
+	codes := make(map[string]string, len(rows))
+	for name, row := range rows {
+		codes[name] = row.ISO3
+	}
+	return reconcileNames(countries, codes, aliases)
+}
+
+// reconcileNames matches named rows to countries. codes maps every row name
+// to its ISO3 code, or to "" when the source has none.
+func reconcileNames(countries []Country, codes map[string]string, aliases map[string]string) ReconciliationReport {
+	var report ReconciliationReport
+
+	byCode := make(map[string]int, len(countries))
+	for i, country := range countries {
+		if country.Cca3 != "" {
+			byCode[country.Cca3] = i
+		}
+	}
+
+	names := make([]string, 0, len(codes))
+	for name := range codes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	matched := make(map[int]bool, len(codes))
+	attach := func(name string, i int, method string) {
+		matched[i] = true
+		report.Matches = append(report.Matches, HDIMatch{HDIName: name, Code: countries[i].Cca3, Country: countries[i].Name, Method: method, index: i})
+	}
+
+	// Exact matches first so that fuzzy matching only sees what is left
+	var pending []string
+	for _, name := range names {
+		if i, ok := byCode[strings.ToUpper(codes[name])]; ok && codes[name] != "" && !matched[i] {
+			attach(name, i, matchISO3)
+		} else if i, ok := byCode[strings.ToUpper(aliases[name])]; ok && !matched[i] {
+			attach(name, i, matchAlias)
+		} else {
+			pending = append(pending, name)
+		}
+	}
+
+	countryKeys := make([][]string, len(countries))
+	for i, country := range countries {
+		countryKeys[i] = countryNameKeys(country)
+	}
+
+	var unmatched []string
+	for _, name := range pending {
+		if i := findByName(hdiNameKeys(name), countryKeys, matched, false); i >= 0 {
+			attach(name, i, matchName)
+		} else {
+			unmatched = append(unmatched, name)
+		}
+	}
+	for _, name := range unmatched {
+		if i := findByName(hdiNameKeys(name), countryKeys, matched, true); i >= 0 {
+			attach(name, i, matchFuzzy)
+		} else {
+			report.UnmatchedRows = append(report.UnmatchedRows, name)
+		}
+	}
+
+	for i, country := range countries {
+		if !matched[i] {
+			report.UnmatchedCountries = append(report.UnmatchedCountries, newCountryRef(country))
+		}
+	}
+	sort.Slice(report.Matches, func(i, j int) bool { return report.Matches[i].HDIName < report.Matches[j].HDIName })
+	sortCountryRefs(report.UnmatchedCountries)
+	return report
+}
+
+// findByName returns the only unmatched country sharing a key with the HDI
+// row, or -1 when there is none or the match is ambiguous. Fuzzy matching
+// also accepts keys one edit apart.
+func findByName(keys []string, countryKeys [][]string, matched map[int]bool, fuzzy bool) int {
+	found := -1
+	for i, candidates := range countryKeys {
+		if matched[i] || !keysMatch(keys, candidates, fuzzy) {
+			continue
+		}
+		if found >= 0 {
+			return -1
+		}
+		found = i
+	}
+	return found
+}
+
+func keysMatch(keys, candidates []string, fuzzy bool) bool {
+	for _, key := range keys {
+		for _, candidate := range candidates {
+			if key == candidate {
+				return true
+			}
+			if fuzzy && len(key) >= 8 && len(candidate) >= 8 && editDistance(key, candidate) <= 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func countryNameKeys(country Country) []string {
+	keys := []string{normalizeName(country.Name), normalizeName(country.OfficialName)}
+	for _, native := range country.NativeNames {
+		keys = append(keys, normalizeName(native.Common))
+	}
+	return keys
+}
+
+var parenthetical = regexp.MustCompile(`^(.*?)\s*\((.*)\)$`)
+
+// hdiNameKeys returns the spellings an annex name may correspond to:
+// "Korea (Republic of)" also stands for "Korea" and "Republic of Korea",
+// "Palestine, State of" for "Palestine" and "State of Palestine".
+func hdiNameKeys(name string) []string {
+	keys := []string{normalizeName(name)}
+	if m := parenthetical.FindStringSubmatch(name); m != nil {
+		keys = append(keys, normalizeName(m[1]), normalizeName(m[2]+" "+m[1]))
+	}
+	if before, after, ok := strings.Cut(name, ","); ok {
+		keys = append(keys, normalizeName(before), normalizeName(after+" "+before))
+	}
+	return keys
+}
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"ç", "c", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i", "ı", "i",
+	"ñ", "n", "ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y",
+	"ş", "s", "ğ", "g",
+)
+
+// normalizeName lower-cases a name, folds common accents and drops
+// punctuation and filler words, so "Côte d'Ivoire" becomes "cote d ivoire".
+func normalizeName(name string) string {
+	name = accents.Replace(strings.ToLower(name))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	kept := words[:0]
+	for _, word := range words {
+		if word != "the" && word != "of" && word != "and" {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
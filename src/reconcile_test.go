@@ -0,0 +1,95 @@
+package src
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReconcileNames(t *testing.T) {
+	countries := []Country{
+		{Name: "Switzerland", OfficialName: "Swiss Confederation", Cca3: "CHE"},
+		{Name: "Turkey", OfficialName: "Republic of Türkiye", Cca3: "TUR"},
+		{Name: "Vietnam", OfficialName: "Socialist Republic of Vietnam", Cca3: "VNM"},
+		{Name: "South Korea", OfficialName: "Republic of Korea", Cca3: "KOR"},
+		{Name: "North Korea", OfficialName: "Democratic People's Republic of Korea", Cca3: "PRK"},
+		{Name: "Kyrgyzstan", OfficialName: "Kyrgyz Republic", Cca3: "KGZ"},
+		{Name: "Luxembourg", OfficialName: "Grand Duchy of Luxembourg", Cca3: "LUX"},
+		{Name: "Slovakia", OfficialName: "Slovak Republic", Cca3: "SVK"},
+		{Name: "Slovenia", OfficialName: "Republic of Slovenia", Cca3: "SVN"},
+	}
+	codes := map[string]string{
+		"Switzerland":                         "CHE",
+		"Türkiye":                             "",
+		"Viet Nam":                            "",
+		"Korea (Republic of)":                 "",
+		"Korea (Democratic People's Rep. of)": "",
+		"Kyrgyzstan":                          "",
+		"Luxemburg":                           "",
+		// One edit away from both Slovakia and Slovenia
+		"Slovekia": "",
+	}
+	aliases := map[string]string{
+		"Türkiye":                             "TUR",
+		"Viet Nam":                            "VNM",
+		"Korea (Democratic People's Rep. of)": "PRK",
+	}
+
+	tests := []struct {
+		name               string
+		aliases            map[string]string
+		matches            map[string]string
+		unmatchedRows      string
+		unmatchedCountries string
+	}{
+		{
+			name:    "with aliases",
+			aliases: aliases,
+			matches: map[string]string{
+				"Switzerland":                         "CHE " + matchISO3,
+				"Türkiye":                             "TUR " + matchAlias,
+				"Viet Nam":                            "VNM " + matchAlias,
+				"Korea (Democratic People's Rep. of)": "PRK " + matchAlias,
+				"Korea (Republic of)":                 "KOR " + matchName,
+				"Kyrgyzstan":                          "KGZ " + matchName,
+				"Luxemburg":                           "LUX " + matchFuzzy,
+			},
+			unmatchedRows:      "Slovekia",
+			unmatchedCountries: "SVK,SVN",
+		},
+		{
+			name: "without aliases",
+			matches: map[string]string{
+				"Switzerland":         "CHE " + matchISO3,
+				"Korea (Republic of)": "KOR " + matchName,
+				"Kyrgyzstan":          "KGZ " + matchName,
+				"Luxemburg":           "LUX " + matchFuzzy,
+			},
+			unmatchedRows:      "Korea (Democratic People's Rep. of),Slovekia,Türkiye,Viet Nam",
+			unmatchedCountries: "PRK,SVK,SVN,TUR,VNM",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := reconcileNames(countries, codes, tt.aliases)
+
+			if len(report.Matches) != len(tt.matches) {
+				t.Errorf("got %d matches, want %d: %+v", len(report.Matches), len(tt.matches), report.Matches)
+			}
+			for _, match := range report.Matches {
+				if got := match.Code + " " + match.Method; got != tt.matches[match.HDIName] {
+					t.Errorf("%s matched %s, want %s", match.HDIName, got, tt.matches[match.HDIName])
+				}
+			}
+			if got := strings.Join(report.UnmatchedRows, ","); got != tt.unmatchedRows {
+				t.Errorf("unmatched rows = %s, want %s", got, tt.unmatchedRows)
+			}
+			var unmatched []string
+			for _, country := range report.UnmatchedCountries {
+				unmatched = append(unmatched, country.Code)
+			}
+			if got := strings.Join(unmatched, ","); got != tt.unmatchedCountries {
+				t.Errorf("unmatched countries = %s, want %s", got, tt.unmatchedCountries)
+			}
+		})
+	}
+}
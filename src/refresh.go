@@ -0,0 +1,164 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dataset is a fully built set of countries. It is never modified after it
+// has been published; a refresh builds a new one and swaps the pointer.
+type dataset struct {
+	countries      []Country
+	graph          *neighbourGraph
+	languages      []LanguageEntry
+	currencies     []CurrencyEntry
+	aggregates     AggregateReport
+	quality        QualityReport
+	reconciliation ReconciliationReport
+	hdiHistory     map[string][]HDIPoint
+	hdiFootnotes   HDIFootnotes
+	indicators     []Indicator
+	loadedAt       time.Time
+	source         SourceInfo
+}
+
+func newDataset(countries []Country, source SourceInfo) *dataset {
+	return &dataset{
+		countries:  countries,
+		graph:      buildNeighbourGraph(countries),
+		languages:  buildLanguageIndex(countries),
+		currencies: buildCurrencyIndex(countries),
+		aggregates: buildAggregates(countries),
+		source:     source,
+	}
+}
+
+var currentData atomic.Pointer[dataset]
+
+// getCountries returns the countries of the current dataset. The slice is
+// shared between requests and must not be modified.
+func getCountries() []Country {
+	data := currentData.Load()
+	if data == nil {
+		return nil
+	}
+	return data.countries
+}
+
+// getDataSource describes the origin and age of the current dataset.
+func getDataSource() SourceInfo {
+	data := currentData.Load()
+	if data == nil {
+		return SourceInfo{}
+	}
+	return data.source
+}
+
+// refresher reloads country and HDI data from its provider, either on a
+// timer or on demand, and publishes it once it passes validation.
+type refresher struct {
+	provider CountryProvider
+	interval time.Duration
+
+	mu sync.Mutex // serialises refreshes
+
+	// Guarded by statusMu rather than mu, so that requests asking for the
+	// status do not wait for a refresh in progress
+	statusMu    sync.Mutex
+	lastAttempt time.Time
+	lastErr     error
+}
+
+func newRefresher(provider CountryProvider, interval time.Duration) *refresher {
+	return &refresher{provider: provider, interval: interval}
+}
+
+// refresh loads a new dataset and swaps it in. The current dataset is kept
+// if loading or validation fails.
+func (r *refresher) refresh() (*dataset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.statusMu.Lock()
+	r.lastAttempt = time.Now()
+	r.statusMu.Unlock()
+
+	data, err := fetchCountries(r.provider)
+	if err == nil {
+		err = validateCountries(data.countries, getCountries())
+	}
+	r.statusMu.Lock()
+	r.lastErr = err
+	r.statusMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	data.loadedAt = time.Now()
+	currentData.Store(data)
+	log.Printf("Country data refreshed: %d countries from %s", len(data.countries), data.source.Name)
+	log.Print(data.quality.summary())
+	return data, nil
+}
+
+// status reports when the last refresh was attempted and why it failed.
+func (r *refresher) status() (time.Time, error) {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	return r.lastAttempt, r.lastErr
+}
+
+// run keeps retrying until a first dataset is available, then refreshes it
+// every interval until the process exits. A zero interval disables periodic
+// refreshes.
+func (r *refresher) run() {
+	delay := degradedRetryDelay
+	for currentData.Load() == nil {
+		time.Sleep(delay)
+		if _, err := r.refresh(); err != nil {
+			delay = min(delay*2, maxDegradedRetryDelay)
+			log.Printf("Warning: Country data still unavailable, retrying in %s: %v", delay, err)
+		}
+	}
+
+	if r.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := r.refresh(); err != nil {
+			log.Printf("Warning: Country data refresh failed, keeping previous data: %v", err)
+		}
+	}
+}
+
+// validateCountries rejects datasets that are obviously broken, such as an
+// empty or truncated upstream response.
+func validateCountries(countries, previous []Country) error {
+	if len(countries) == 0 {
+		return errors.New("dataset contains no countries")
+	}
+	if len(countries) < len(previous)/2 {
+		return fmt.Errorf("dataset shrank from %d to %d countries", len(previous), len(countries))
+	}
+	for i, country := range countries {
+		if country.Name == "" {
+			return fmt.Errorf("country at index %d has no name", i)
+		}
+	}
+	return nil
+}
+
+func parseRefreshInterval(value string) time.Duration {
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: Invalid REFRESH_INTERVAL %q, periodic refresh disabled: %v", value, err)
+		return 0
+	}
+	return interval
+}
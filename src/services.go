@@ -1,66 +1,64 @@
 package src
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"math"
 	"net/http"
-	"os"
+	"net/url"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func fetchCountries() ([]Country, error) {
-	// Read HDI data from the CSV file
-	hdiContent, err := os.ReadFile("HDR23-24_Statistical_Annex_HDI_Table - HDI.csv")
+// fetchCountries loads countries from the provider, merges in every HDI annex
+// and builds the indexes and quality report of a new dataset.
+func fetchCountries(provider CountryProvider) (*dataset, error) {
+	annexes, err := loadHDIAnnexes(getEnv("HDI_DIR", defaultHDIDir))
 	if err != nil {
 		log.Printf("Warning: Could not load HDI data: %v", err)
 	}
 
-	hdiMap := parseHDIData(string(hdiContent))
-
-	// Fetch countries from the REST API
-	resp, err := http.Get("https://restcountries.com/v3.1/all?fields=name,capital,region,flag,timezones,population,area,languages,currencies,idd,car,borders")
+	rawCountries, info, err := provider.Countries()
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	log.Printf("Loaded %d countries from %s (fetched %s)", len(rawCountries), info.Name, formatAge(info.Age()))
 
-	body, err := io.ReadAll(resp.Body)
+	aliases, err := loadHDIAliases(hdiAliasesFile)
 	if err != nil {
-		return nil, err
+		log.Printf("Warning: Could not load HDI aliases: %v", err)
 	}
 
-	var rawCountries []struct {
-		Name struct {
-			Common string `json:"common"`
-		} `json:"name"`
-		Capital    []string               `json:"capital"`
-		Region     string                 `json:"region"`
-		Flag       string                 `json:"flag"`
-		TimeZones  []string               `json:"timezones"`
-		Population int                    `json:"population"`
-		Area       float64                `json:"area"`
-		Languages  map[string]string      `json:"languages"`
-		Currencies map[string]interface{} `json:"currencies"`
-		IDD        struct {
-			Root     string   `json:"root"`
-			Suffixes []string `json:"suffixes"`
-		} `json:"idd"`
-		Car struct {
-			Side string `json:"side"`
-		} `json:"car"`
-		Borders []string `json:"borders"`
-	}
-
-	if err := json.Unmarshal(body, &rawCountries); err != nil {
-		return nil, err
+	tz := timeZoneData{
+		table:    loadZoneTable(getEnv("ZONEINFO", defaultZoneInfoDir)),
+		polygons: timeZonePolygons(),
+	}
+
+	countries := buildCountries(rawCountries, tz)
+	history, reconciliation := mergeHDI(countries, annexes, aliases)
+	rankHDI(countries)
+	log.Printf("HDI reconciliation: %d matched, %d rows and %d countries unmatched",
+		len(reconciliation.Matches), len(reconciliation.UnmatchedRows), len(reconciliation.UnmatchedCountries))
+
+	indicators := loadIndicators(getEnv("INDICATORS_DIR", defaultIndicatorsDir), countries)
+
+	data := newDataset(countries, info)
+	data.reconciliation = reconciliation
+	data.hdiHistory = history
+	if len(annexes) > 0 {
+		data.hdiFootnotes = annexes[len(annexes)-1].footnotes
 	}
+	data.indicators = indicators
+	data.quality = checkDataQuality(countries, reconciliation)
+	return data, nil
+}
 
+// buildCountries converts the raw records, placing each country in the IANA
+// zone of its capital, see representativeZone.
+func buildCountries(rawCountries []RawCountry, tz timeZoneData) []Country {
 	countries := make([]Country, 0, len(rawCountries))
 	for _, rc := range rawCountries {
 		capital := ""
@@ -68,162 +66,282 @@ func fetchCountries() ([]Country, error) {
 			capital = rc.Capital[0]
 		}
 
-		mainTimeZone := "UTC"
-		if len(rc.TimeZones) > 0 {
-			mainTimeZone = rc.TimeZones[0]
-		}
-
-		currentTime := calculateTime(mainTimeZone)
+		timeZones, invalidTimeZones := parseUTCOffsets(rc.TimeZones)
+		ianaZones, ianaZone, zoneSource := representativeZone(rc.Cca2, rc.CapitalInfo.LatLng, timeZones, tz)
+		mainTimeZone := mainOffset(ianaZone, timeZones)
+		sort.Slice(timeZones, func(i, j int) bool { return timeZones[i] < timeZones[j] })
 
-		population := formatNumber(rc.Population)
+		languages := make([]Language, 0, len(rc.Languages))
+		for code, name := range rc.Languages {
+			languages = append(languages, Language{Code: code, Name: name})
+		}
+		sort.Slice(languages, func(i, j int) bool {
+			if languages[i].Name != languages[j].Name {
+				return languages[i].Name < languages[j].Name
+			}
+			return languages[i].Code < languages[j].Code
+		})
 
-		var languages []string
-		for _, lang := range rc.Languages {
-			languages = append(languages, lang)
+		// Sort by code so that the first currency is stable across restarts
+		currencies := make([]Currency, 0, len(rc.Currencies))
+		for code, curr := range rc.Currencies {
+			currencies = append(currencies, Currency{Code: code, Name: curr.Name, Symbol: curr.Symbol})
 		}
+		sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
 
 		currency := ""
-		for _, curr := range rc.Currencies {
-			if c, ok := curr.(map[string]interface{}); ok {
-				if name, ok := c["name"].(string); ok {
-					currency = name
-					break
-				}
-			}
+		if len(currencies) > 0 {
+			currency = currencies[0].Name
 		}
 
-		callingCode := ""
+		nativeNames := make([]NativeName, 0, len(rc.Name.NativeName))
+		for lang, name := range rc.Name.NativeName {
+			nativeNames = append(nativeNames, NativeName{Language: lang, Official: name.Official, Common: name.Common})
+		}
+		sort.Slice(nativeNames, func(i, j int) bool { return nativeNames[i].Language < nativeNames[j].Language })
+
+		var callingCodes []string
 		if rc.IDD.Root != "" {
 			if len(rc.IDD.Suffixes) > 0 {
-				callingCode = rc.IDD.Root + rc.IDD.Suffixes[0]
+				for _, suffix := range rc.IDD.Suffixes {
+					callingCodes = append(callingCodes, rc.IDD.Root+suffix)
+				}
 			} else {
-				callingCode = rc.IDD.Root
+				callingCodes = []string{rc.IDD.Root}
 			}
 		}
 
-		// Add HDI data if available
-		hdiData, hasHDI := hdiMap[rc.Name.Common]
-		if !hasHDI {
-			hdiData = HDIData{} // Empty HDI data if not found
+		callingCode := ""
+		if len(callingCodes) > 0 {
+			callingCode = callingCodes[0]
 		}
 
 		country := Country{
-			Name:        rc.Name.Common,
-			TimeZone:    mainTimeZone,
-			Capital:     capital,
-			Region:      rc.Region,
-			Flag:        rc.Flag,
-			TimeZones:   rc.TimeZones,
-			CurrentTime: currentTime,
-			IsFavorite:  contains(favorites.Countries, rc.Name.Common),
-			Population:  population,
-			Area:        rc.Area,
-			Languages:   languages,
-			Currency:    currency,
-			CallingCode: callingCode,
-			DrivingSide: strings.Title(rc.Car.Side),
-			Borders:     rc.Borders,
-			HDI:         hdiData,
+			Name:          rc.Name.Common,
+			OfficialName:  rc.Name.Official,
+			NativeNames:   nativeNames,
+			Cca2:          rc.Cca2,
+			Cca3:          rc.Cca3,
+			Ccn3:          rc.Ccn3,
+			TimeZone:      mainTimeZone,
+			Capital:       capital,
+			Capitals:      rc.Capital,
+			Region:        rc.Region,
+			Flag:          rc.Flag,
+			TimeZones:     timeZones,
+			IANAZones:     ianaZones,
+			IANAZone:      ianaZone,
+			ZoneSource:    zoneSource,
+			CapitalLatLng: rc.CapitalInfo.LatLng,
+			IsFavorite:    contains(favorites.Countries, rc.Name.Common),
+			Population:    rc.Population,
+			Area:          rc.Area,
+			Languages:     languages,
+			Currency:      currency,
+			Currencies:    currencies,
+			CallingCode:   callingCode,
+			CallingCodes:  callingCodes,
+			DrivingSide:   strings.Title(rc.Car.Side),
+			Borders:       rc.Borders,
+
+			invalidTimeZones: invalidTimeZones,
 		}
 		countries = append(countries, country)
 	}
 
-	return countries, nil
+	resolveNeighbours(countries)
+	computeDerivedMetrics(countries)
+	return countries
 }
 
-func parseHDIData(csvContent string) map[string]HDIData {
-	hdiMap := make(map[string]HDIData)
-	lines := strings.Split(csvContent, "\n")
-
-	log.Printf("Starting HDI data parsing with %d lines", len(lines))
+// computeDerivedMetrics fills in the per-country figures that depend on
+// population: density in people per km² and the percentage of the world
+// population.
+func computeDerivedMetrics(countries []Country) {
+	total := 0
+	for _, country := range countries {
+		total += country.Population
+	}
 
-	var currentCategory string
-	for _, line := range lines {
-		// Skip empty lines
-		if strings.TrimSpace(line) == "" {
-			continue
+	for i := range countries {
+		if countries[i].Area > 0 {
+			countries[i].PopulationDensity = float64(countries[i].Population) / countries[i].Area
 		}
-
-		// Split the line by comma, handling quoted fields
-		fields := strings.Split(line, ",")
-
-		// Clean up each field
-		for i := range fields {
-			fields[i] = strings.Trim(fields[i], `" `)
+		if total > 0 {
+			countries[i].WorldPopulationShare = 100 * float64(countries[i].Population) / float64(total)
 		}
+	}
+}
 
-		// Check if this is a category line
-		if strings.Contains(fields[0], "VERY HIGH HUMAN DEVELOPMENT") ||
-			strings.Contains(fields[0], "HIGH HUMAN DEVELOPMENT") ||
-			strings.Contains(fields[0], "MEDIUM HUMAN DEVELOPMENT") ||
-			strings.Contains(fields[0], "LOW HUMAN DEVELOPMENT") {
-			currentCategory = fields[0]
-			log.Printf("Found category: %s", currentCategory)
-			continue
-		}
+// Value of timerange_zone restricting the time range to the capital's zone
+const capitalZoneOnly = "capital"
+
+// countryFilter holds the home page filters. Empty fields match every
+// country. The HDI bounds are kept as given in the query string and checked
+// by newCountryFilter.
+type countryFilter struct {
+	Region        string
+	TimeZone      string
+	TimeRange     string
+	TimeRangeZone string
+	Language      string
+	Currency      string
+	HDICategory   string
+	HDIMin        string
+	HDIMax        string
+	LifeMin       string
+	LifeMax       string
+	// Bounds on expected and mean years of schooling
+	ExpectedSchoolingMin string
+	ExpectedSchoolingMax string
+	MeanSchoolingMin     string
+	MeanSchoolingMax     string
+}
 
-		// Try to parse rank number to verify if this is a country line
-		rank := strings.TrimSpace(fields[0])
-		rankNum, err := strconv.Atoi(rank)
+// boundParams are the numeric filter parameters.
+var boundParams = []string{"hdi_min", "hdi_max", "life_min", "life_max",
+	"expected_schooling_min", "expected_schooling_max", "mean_schooling_min", "mean_schooling_max"}
+
+// newCountryFilter reads the filters from a query string. When a bound is
+// not a finite number it returns the name of that parameter.
+func newCountryFilter(values url.Values) (countryFilter, string) {
+	filter := countryFilter{
+		Region:               values.Get("region"),
+		TimeZone:             values.Get("timezone"),
+		TimeRange:            values.Get("timerange"),
+		TimeRangeZone:        values.Get("timerange_zone"),
+		Language:             values.Get("language"),
+		Currency:             values.Get("currency"),
+		HDICategory:          values.Get("hdi_category"),
+		HDIMin:               values.Get("hdi_min"),
+		HDIMax:               values.Get("hdi_max"),
+		LifeMin:              values.Get("life_min"),
+		LifeMax:              values.Get("life_max"),
+		ExpectedSchoolingMin: values.Get("expected_schooling_min"),
+		ExpectedSchoolingMax: values.Get("expected_schooling_max"),
+		MeanSchoolingMin:     values.Get("mean_schooling_min"),
+		MeanSchoolingMax:     values.Get("mean_schooling_max"),
+	}
+
+	// Normalise the time zone so that "UTC+00:00" selects the same as "UTC"
+	if filter.TimeZone != "" {
+		offset, err := ParseUTCOffset(filter.TimeZone)
 		if err != nil {
-			continue // Not a country line
+			return filter, "timezone"
 		}
+		filter.TimeZone = offset.String()
+	}
+	if filter.TimeRange != "" && !slices.Contains(timeRanges, filter.TimeRange) {
+		return filter, "timerange"
+	}
+	if filter.TimeRangeZone != "" && filter.TimeRangeZone != capitalZoneOnly {
+		return filter, "timerange_zone"
+	}
 
-		countryName := strings.TrimSpace(fields[1])
-		if countryName == "" {
-			continue
+	for _, param := range boundParams {
+		if value := filter.params()[param]; value != "" {
+			bound, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(bound) || math.IsInf(bound, 0) {
+				return filter, param
+			}
 		}
+	}
+	return filter, ""
+}
 
-		// Parse HDI values, with better error handling
-		hdiValue, err := strconv.ParseFloat(strings.Trim(fields[2], `" `), 64)
-		if err != nil {
-			log.Printf("Warning: Could not parse HDI value for %s: %v", countryName, err)
-			continue
-		}
+// params returns the filter as query string parameters.
+func (f countryFilter) params() map[string]string {
+	return map[string]string{
+		"region":                 f.Region,
+		"timezone":               f.TimeZone,
+		"timerange":              f.TimeRange,
+		"timerange_zone":         f.TimeRangeZone,
+		"language":               f.Language,
+		"currency":               f.Currency,
+		"hdi_category":           f.HDICategory,
+		"hdi_min":                f.HDIMin,
+		"hdi_max":                f.HDIMax,
+		"life_min":               f.LifeMin,
+		"life_max":               f.LifeMax,
+		"expected_schooling_min": f.ExpectedSchoolingMin,
+		"expected_schooling_max": f.ExpectedSchoolingMax,
+		"mean_schooling_min":     f.MeanSchoolingMin,
+		"mean_schooling_max":     f.MeanSchoolingMax,
+	}
+}
 
-		lifeExpect, _ := strconv.ParseFloat(strings.Trim(fields[4], `" `), 64)
-		schoolYears, _ := strconv.ParseFloat(strings.Trim(fields[6], `" `), 64)
-		gniPerCap := strings.Trim(fields[8], `" `)
+func (f countryFilter) isEmpty() bool {
+	return f == countryFilter{}
+}
 
-		log.Printf("Successfully parsed HDI data for country: %s (rank: %d, value: %.3f)",
-			countryName, rankNum, hdiValue)
+func (f countryFilter) matches(country Country) bool {
+	return (f.Region == "" || country.Region == f.Region) &&
+		(f.TimeZone == "" || hasTimeZone(country, f.TimeZone)) &&
+		(f.TimeRange == "" || f.inTimeRange(country)) &&
+		(f.Language == "" || hasLanguage(country, f.Language)) &&
+		(f.Currency == "" || hasCurrency(country, f.Currency)) &&
+		(f.HDICategory == "" || country.HDI.Category == f.HDICategory) &&
+		inRange(country.HDI.HDIValue, f.HDIMin, f.HDIMax) &&
+		inRange(country.HDI.LifeExpect, f.LifeMin, f.LifeMax) &&
+		inRange(country.HDI.ExpectedSchooling, f.ExpectedSchoolingMin, f.ExpectedSchoolingMax) &&
+		inRange(country.HDI.MeanSchooling, f.MeanSchoolingMin, f.MeanSchoolingMax)
+}
 
-		hdiMap[countryName] = HDIData{
-			HDIRank:     rankNum,
-			HDIValue:    hdiValue,
-			Category:    currentCategory,
-			LifeExpect:  lifeExpect,
-			SchoolYears: schoolYears,
-			GNIPerCap:   gniPerCap,
+// inTimeRange matches a country when any of its zones is in the time range,
+// or only its capital's zone with timerange_zone=capital.
+func (f countryFilter) inTimeRange(country Country) bool {
+	if f.TimeRangeZone == capitalZoneOnly || len(country.ZoneTimes) == 0 {
+		return isInTimeRange(country.CurrentTime, f.TimeRange)
+	}
+	for _, zone := range country.ZoneTimes {
+		if isInTimeRange(zone.Time, f.TimeRange) {
+			return true
 		}
 	}
+	return false
+}
 
-	log.Printf("Finished parsing HDI data. Found data for %d countries", len(hdiMap))
-
-	// Debug output of all country names we have HDI data for
-	log.Printf("Countries with HDI data:")
-	for countryName := range hdiMap {
-		log.Printf("- %s", countryName)
+// inRange reports whether value lies within the optional bounds. A zero
+// value means the annex has no figure and never matches a bound.
+func inRange(value float64, min, max string) bool {
+	if min == "" && max == "" {
+		return true
 	}
-
-	return hdiMap
+	if value == 0 {
+		return false
+	}
+	if lower, err := strconv.ParseFloat(min, 64); err == nil && value < lower {
+		return false
+	}
+	if upper, err := strconv.ParseFloat(max, 64); err == nil && value > upper {
+		return false
+	}
+	return true
 }
 
-func filterCountries(countries []Country, region, timezone, timeRange string, w http.ResponseWriter, r *http.Request) []Country {
-	if region == "" && timezone == "" && timeRange == "" {
+func (f countryFilter) apply(countries []Country) []Country {
+	if f.isEmpty() {
 		return countries
 	}
 
 	var filtered []Country
 	for _, country := range countries {
-		if (region == "" || country.Region == region) &&
-			(timezone == "" || contains(country.TimeZones, timezone)) &&
-			(timeRange == "" || isInTimeRange(country.CurrentTime, timeRange)) {
+		if f.matches(country) {
 			filtered = append(filtered, country)
 		}
 	}
+	return filtered
+}
+
+func filterCountries(countries []Country, filter countryFilter, w http.ResponseWriter, r *http.Request) []Country {
+	if filter.isEmpty() {
+		return countries
+	}
+
+	filtered := filter.apply(countries)
 
-	if len(filtered) == 0 && (timezone != "" || timeRange != "") {
+	// A region always has countries, so only the other filters can come up empty
+	if len(filtered) == 0 && filter != (countryFilter{Region: filter.Region}) {
 		http.Redirect(w, r, "/error", http.StatusSeeOther)
 		return nil
 	}
@@ -286,66 +404,197 @@ func getUniqueRegions(countries []Country) []string {
 	return uniqueRegions
 }
 
-// getUniqueTimeZones gets unique time zones from countries and standard list
-func getUniqueTimeZones(countries []Country) []string {
-	zones := make(map[string]bool)
-
-	// Add all standard time zones first
-	for _, tz := range standardTimeZones {
-		zones[tz] = true
+// hasTimeZone reports whether a country spans the offset, given in canonical
+// form.
+func hasTimeZone(country Country, timeZone string) bool {
+	for _, offset := range country.TimeZones {
+		if offset.String() == timeZone {
+			return true
+		}
 	}
+	return false
+}
 
-	// Add any additional zones from countries
+// getUniqueTimeZones gets unique time zones from countries and standard list,
+// from west to east
+func getUniqueTimeZones(countries []Country) []UTCOffset {
+	standard, _ := parseUTCOffsets(standardTimeZones)
+	zones := make(map[UTCOffset]bool)
+	for _, offset := range standard {
+		zones[offset] = true
+	}
 	for _, country := range countries {
-		for _, tz := range country.TimeZones {
-			cleanTZ := strings.TrimSpace(tz)
-			if cleanTZ != "" {
-				zones[cleanTZ] = true
-			}
+		for _, offset := range country.TimeZones {
+			zones[offset] = true
 		}
 	}
 
-	var uniqueZones []string
+	var uniqueZones []UTCOffset
 	for zone := range zones {
 		uniqueZones = append(uniqueZones, zone)
 	}
-	sort.Strings(uniqueZones)
+	sort.Slice(uniqueZones, func(i, j int) bool { return uniqueZones[i] < uniqueZones[j] })
 	return uniqueZones
 }
 
-func calculateTime(timezone string) string {
-	offset := strings.TrimPrefix(timezone, "UTC")
-	if offset == "" {
-		return time.Now().UTC().Format("15:04")
+// now is the clock behind every local time. Handlers read it once per
+// request so that all countries on a page, the time filters and the API use
+// the same instant. Fixtures can replace it with a fixed time.
+var now = time.Now
+
+// Layouts accepted by parseInstant for a local time followed by a zone
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// parseInstant reads the at parameter: an RFC 3339 time such as
+// "2025-03-13T14:00:00Z", or a local time followed by an IANA zone or UTC
+// offset, such as "2025-03-13 14:00 Europe/Paris" or "2025-03-13T14:00
+// UTC+01:00". Local times that the clocks skip or show twice are rejected,
+// since they name no single instant.
+func parseInstant(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
 	}
 
-	var hours, minutes int
-	fmt.Sscanf(offset, "%d:%d", &hours, &minutes)
+	i := strings.LastIndex(value, " ")
+	if i < 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q, use RFC 3339 or a local time and zone", value)
+	}
+	clock, zone := value[:i], value[i+1:]
+	loc, err := loadLocation(zone)
+	if err != nil || strings.EqualFold(zone, "local") {
+		offset, offsetErr := ParseUTCOffset(zone)
+		if offsetErr != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %q", zone)
+		}
+		loc = offset.Location()
+	}
+	for _, layout := range localTimeLayouts {
+		wall, err := time.ParseInLocation(layout, strings.TrimSpace(clock), time.UTC)
+		if err != nil {
+			continue
+		}
+		switch instants := localInstants(wall, loc); len(instants) {
+		case 0:
+			return time.Time{}, fmt.Errorf("%s does not exist in %s, the clocks skip it", wall.Format("2006-01-02 15:04"), zone)
+		case 1:
+			return instants[0], nil
+		default:
+			return time.Time{}, fmt.Errorf("%s happens twice in %s, the clocks go back; give an RFC 3339 time with its offset", wall.Format("2006-01-02 15:04"), zone)
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid local time %q", clock)
+}
 
-	now := time.Now().UTC()
-	localTime := now.Add(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute)
-	return localTime.Format("15:04")
+// withLocalTimes returns a copy of the countries with their local time, date
+// and offset, the derived time of day and the time differences with their
+// neighbours set for the instant at. The shared
+// dataset never stores local times, which would go stale on a long-running
+// server.
+func withLocalTimes(countries []Country, at time.Time) []Country {
+	result := make([]Country, len(countries))
+	for i, country := range countries {
+		local := at.In(countryLocation(country))
+		country.CurrentTime, country.CurrentOffset = local.Format("15:04"), offsetAt(local)
+		country.CurrentDate = local.Format("2006-01-02")
+		country.DayShift = calendarDays(at.UTC(), local)
+		country.ZoneTimes = zoneTimes(country, at)
+		country.Neighbours = neighboursAt(country, at)
+		country.TimeOfDay = timeOfDay(country.CurrentTime)
+		country.DayNight = dayNight(country, at)
+		result[i] = country
+	}
+	return result
 }
 
-func isInTimeRange(currentTime, timeRange string) bool {
-	if timeRange == "" {
-		return true
+// zoneTimes returns the local time in each zone of a country at the given
+// instant, from west to east. IANA zones on the same offset at that instant
+// are shown once, by the capital's zone or the first in the zone table.
+// Listed UTC offsets that none of the IANA zones observes in the year of the
+// instant, such as those of overseas territories, are added as fixed zones.
+func zoneTimes(country Country, at time.Time) []ZoneTime {
+	var times []ZoneTime
+	seen := make(map[UTCOffset]bool)
+	observed := make(map[UTCOffset]bool)
+	add := func(zone string, loc *time.Location, capital bool) {
+		localTime := at.In(loc)
+		offset := offsetAt(localTime)
+		if seen[offset] {
+			return
+		}
+		seen[offset] = true
+		times = append(times, ZoneTime{
+			Zone:     zone,
+			Offset:   offset,
+			Time:     localTime.Format("15:04"),
+			Date:     localTime.Format("2006-01-02"),
+			DayShift: calendarDays(at.UTC(), localTime),
+			Capital:  capital,
+		})
+	}
+
+	zones := country.IANAZones
+	if country.IANAZone != "" {
+		zones = append([]string{country.IANAZone}, zones...)
 	}
-	// Parse the current time
+	for _, zone := range zones {
+		loc, err := loadLocation(zone)
+		if err != nil {
+			continue
+		}
+		winter, summer := zoneOffsets(zone, at.Year())
+		observed[winter], observed[summer] = true, true
+		add(zone, loc, zone == country.IANAZone)
+	}
+
+	if country.IANAZone == "" {
+		add("", country.TimeZone.Location(), true)
+	}
+	for _, offset := range country.TimeZones {
+		if !observed[offset] {
+			add("", offset.Location(), false)
+		}
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Offset < times[j].Offset })
+	return times
+}
+
+// Values of the timerange filter, six hours each from midnight
+var timeRanges = []string{"night", "morning", "afternoon", "evening"}
+
+// timeOfDay classifies a "15:04" clock time into one of timeRanges.
+func timeOfDay(currentTime string) string {
 	hours, err := strconv.Atoi(strings.Split(currentTime, ":")[0])
-	if err != nil {
-		return false
+	if err != nil || hours < 0 || hours >= 24 {
+		return ""
 	}
-	switch timeRange {
-	case "night":
-		return hours >= 0 && hours < 6
-	case "morning":
-		return hours >= 6 && hours < 12
-	case "afternoon":
-		return hours >= 12 && hours < 18
-	case "evening":
-		return hours >= 18 && hours < 24
-	default:
-		return true
+	return timeRanges[hours/6]
+}
+
+// isInTimeRange reports whether the clock time falls in the range, one of
+// timeRanges (newCountryFilter rejects the others).
+func isInTimeRange(currentTime, timeRange string) bool {
+	return timeOfDay(currentTime) == timeRange
+}
+
+// dayNight tells whether the sun is up at the capital at the given instant.
+// Countries without capital coordinates count as day from 06:00 to 18:00
+// local time.
+func dayNight(country Country, at time.Time) string {
+	if len(country.CapitalLatLng) == 2 {
+		if sunElevation(at, country.CapitalLatLng[0], country.CapitalLatLng[1]) > sunriseElevation {
+			return "day"
+		}
+		return "night"
+	}
+	if hours := at.In(countryLocation(country)).Hour(); hours >= 6 && hours < 18 {
+		return "day"
 	}
+	return "night"
 }
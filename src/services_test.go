@@ -0,0 +1,191 @@
+package src
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCountryFilterRejectsBadValues(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"hdi_min=0.8", ""},
+		{"mean_schooling_max=12.5", ""},
+		{"hdi_min=high", "hdi_min"},
+		{"hdi_max=NaN", "hdi_max"},
+		{"life_min=-Inf", "life_min"},
+		{"life_max=%2BInf", "life_max"},
+		{"expected_schooling_max=1e309", "expected_schooling_max"},
+		{"mean_schooling_min=inf", "mean_schooling_min"},
+		{"timerange=night", ""},
+		{"timerange=evening", ""},
+		{"timerange=night-1", "timerange"},
+		{"timerange=noon", "timerange"},
+		{"timerange_zone=all", "timerange_zone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, badParam := newCountryFilter(values); badParam != tt.want {
+				t.Errorf("newCountryFilter(%s) = %q, want %q", tt.query, badParam, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountryFilterSchoolingBounds(t *testing.T) {
+	country := Country{Name: "Iceland", HDI: HDIData{ExpectedSchooling: 19.1, MeanSchooling: 13.8}}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"expected_schooling_min=19", true},
+		{"expected_schooling_min=20", false},
+		{"mean_schooling_min=13", true},
+		{"mean_schooling_max=13", false},
+		{"expected_schooling_min=19&mean_schooling_max=13", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			filter, badParam := newCountryFilter(values)
+			if badParam != "" {
+				t.Fatalf("invalid %s", badParam)
+			}
+			if got := filter.matches(country); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInstant(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string // RFC 3339 in UTC, empty for an error
+	}{
+		{"2025-03-13T14:00:00Z", "2025-03-13T14:00:00Z"},
+		{"2025-03-13T14:00:00+05:30", "2025-03-13T08:30:00Z"},
+		{"2025-03-13 14:00 Europe/Paris", "2025-03-13T13:00:00Z"},
+		{"2025-07-13T14:00 Europe/Paris", "2025-07-13T12:00:00Z"},
+		{"2025-03-13T14:00 UTC+01:00", "2025-03-13T13:00:00Z"},
+		{"2025-03-30 02:30 Europe/Paris", ""},     // the clocks skip 02:00 to 03:00
+		{"2025-10-26 02:30 Europe/Paris", ""},     // the clocks show 02:00 to 03:00 twice
+		{"2025-03-09T02:15 America/New_York", ""}, // skipped
+		{"2025-03-09T03:15 America/New_York", "2025-03-09T07:15:00Z"},
+		{"2025-03-13 14:00 Mars/Olympus", ""},
+		{"yesterday", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseInstant(tt.value)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("parseInstant() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseInstant() error = %v", err)
+			}
+			if got.UTC().Format(time.RFC3339) != tt.want {
+				t.Errorf("parseInstant() = %v, want %s", got.UTC().Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithLocalTimesDayShift(t *testing.T) {
+	country := Country{
+		Name:      "Kiribati",
+		IANAZone:  "Pacific/Kiritimati",
+		IANAZones: []string{"Pacific/Tarawa", "Pacific/Kanton", "Pacific/Kiritimati"},
+		TimeZones: []UTCOffset{12 * 60, 13 * 60, 14 * 60},
+	}
+	at := time.Date(2025, 3, 13, 20, 0, 0, 0, time.UTC)
+	got := withLocalTimes([]Country{country}, at)[0]
+	if got.CurrentDate != "2025-03-14" || got.DayShift != 1 {
+		t.Errorf("got %s (%+d), want 2025-03-14 (+1)", got.CurrentDate, got.DayShift)
+	}
+	for _, zone := range got.ZoneTimes {
+		if zone.Date != "2025-03-14" || zone.DayShift != 1 {
+			t.Errorf("%s: got %s (%+d), want 2025-03-14 (+1)", zone.Name(), zone.Date, zone.DayShift)
+		}
+	}
+
+	country = Country{Name: "Samoa", IANAZone: "Pacific/Pago_Pago", TimeZones: []UTCOffset{-11 * 60}}
+	got = withLocalTimes([]Country{country}, time.Date(2025, 3, 13, 5, 0, 0, 0, time.FixedZone("", 9*60*60)))[0]
+	if got.CurrentDate != "2025-03-12" || got.DayShift != 0 {
+		t.Errorf("got %s (%+d), want 2025-03-12 (+0) against the UTC date", got.CurrentDate, got.DayShift)
+	}
+}
+
+func TestZoneTimesCapital(t *testing.T) {
+	at := time.Date(2010, time.January, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		country Country
+		want    []string // names of the zone times, the capital's marked with *
+	}{
+		{
+			"IANA capital zone",
+			Country{IANAZone: "Europe/Paris", IANAZones: []string{"Europe/Paris"}, TimeZones: []UTCOffset{60}},
+			[]string{"Europe/Paris*"},
+		},
+		{
+			"fixed offset capital",
+			Country{TimeZone: -300, TimeZones: []UTCOffset{-300, 60}},
+			[]string{"UTC-05:00*", "UTC+01:00"},
+		},
+		{
+			// Moscow kept summer time until 2011, so UTC+04:00 was observed
+			"offsets observed in the year of the instant",
+			Country{IANAZone: "Europe/Moscow", IANAZones: []string{"Europe/Moscow"}, TimeZones: []UTCOffset{180, 240}},
+			[]string{"Europe/Moscow*"},
+		},
+		{
+			// The capital's offset is already taken by a zone of the table
+			"fixed offset capital on a seen offset",
+			Country{TimeZone: 60, IANAZones: []string{"Europe/Paris"}, TimeZones: []UTCOffset{-300, 60}},
+			[]string{"UTC-05:00", "Europe/Paris"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, zone := range zoneTimes(tt.country, at) {
+				name := zone.Name()
+				if zone.Capital {
+					name += "*"
+				}
+				got = append(got, name)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountryFilterNightRange(t *testing.T) {
+	at := time.Date(2025, time.January, 15, 2, 0, 0, 0, time.UTC)
+	countries := withLocalTimes([]Country{
+		{Name: "Iceland", IANAZone: "Atlantic/Reykjavik", IANAZones: []string{"Atlantic/Reykjavik"}},
+		{Name: "Japan", IANAZone: "Asia/Tokyo", IANAZones: []string{"Asia/Tokyo"}},
+	}, at)
+
+	filter, badParam := newCountryFilter(url.Values{"timerange": {"night"}})
+	if badParam != "" {
+		t.Fatalf("invalid %s", badParam)
+	}
+	got := filter.apply(countries)
+	if len(got) != 1 || got[0].Name != "Iceland" {
+		t.Errorf("night at 02:00 UTC matched %v, want only Iceland", got)
+	}
+}
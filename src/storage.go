@@ -2,7 +2,9 @@ package src
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"time"
 )
 
 func loadFavorites() error {
@@ -24,3 +26,53 @@ func saveFavorites() error {
 	}
 	return os.WriteFile(favoritesFile, data, 0644)
 }
+
+func loadCountriesSnapshot(path string) (CountrySnapshot, error) {
+	var snapshot CountrySnapshot
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	if err := json.Unmarshal(file, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return snapshot, fmt.Errorf("unsupported snapshot version %d in %s", snapshot.Version, path)
+	}
+	var countries []json.RawMessage
+	if err := json.Unmarshal(snapshot.Countries, &countries); err != nil || len(countries) == 0 {
+		return snapshot, fmt.Errorf("snapshot %s contains no countries", path)
+	}
+	return snapshot, nil
+}
+
+func saveCountriesSnapshot(path string, countries []byte) error {
+	snapshot := CountrySnapshot{
+		Version:   snapshotVersion,
+		Source:    countriesAPIURL,
+		FetchedAt: time.Now().UTC(),
+		Countries: countries,
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadHDIAliases reads the table mapping HDI annex names to cca3 codes. A
+// missing file is not an error.
+func loadHDIAliases(path string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	file, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return aliases, nil
+		}
+		return aliases, err
+	}
+	if err := json.Unmarshal(file, &aliases); err != nil {
+		return aliases, fmt.Errorf("invalid alias table %s: %w", path, err)
+	}
+	return aliases, nil
+}
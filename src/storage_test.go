@@ -0,0 +1,69 @@
+package src
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fixtureSnapshot = "testdata/countries-snapshot.json"
+
+func TestFileProviderReadsFixtureSnapshot(t *testing.T) {
+	rawCountries, info, err := newFileProvider(fixtureSnapshot).Countries()
+	if err != nil {
+		t.Fatalf("Countries() error = %v", err)
+	}
+	if len(rawCountries) != 5 {
+		t.Errorf("got %d countries, want 5", len(rawCountries))
+	}
+	if want := time.Date(2025, 3, 13, 10, 0, 0, 0, time.UTC); !info.FetchedAt.Equal(want) {
+		t.Errorf("FetchedAt = %v, want %v", info.FetchedAt, want)
+	}
+	if info.FromCache {
+		t.Error("FromCache = true for a snapshot")
+	}
+	if rawCountries[0].Name.Common != "Switzerland" || rawCountries[0].Cca3 != "CHE" {
+		t.Errorf("first country = %s (%s), want Switzerland (CHE)", rawCountries[0].Name.Common, rawCountries[0].Cca3)
+	}
+}
+
+func TestLoadCountriesSnapshotRejectsBadFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"version 0", `{"version": 0, "countries": [{}]}`, "unsupported snapshot version 0"},
+		{"future version", `{"version": 2, "countries": [{}]}`, "unsupported snapshot version 2"},
+		{"no countries", `{"version": 1, "countries": []}`, "contains no countries"},
+		{"not JSON", `countries`, "invalid snapshot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadCountriesSnapshot(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadCountriesSnapshot() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveCountriesSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := saveCountriesSnapshot(path, []byte(`[{"name":{"common":"Iceland"},"cca3":"ISL"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	rawCountries, _, err := newFileProvider(path).Countries()
+	if err != nil {
+		t.Fatalf("Countries() error = %v", err)
+	}
+	if len(rawCountries) != 1 || rawCountries[0].Cca3 != "ISL" {
+		t.Errorf("got %+v, want Iceland", rawCountries)
+	}
+}
@@ -1,7 +1,10 @@
 package src
 
 import (
+	"fmt"
+	"os"
 	"strconv"
+	"time"
 )
 
 func contains(slice []string, str string) bool {
@@ -41,3 +44,48 @@ func formatNumber(n int) string {
 	}
 	return string(out)
 }
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// formatAge renders a duration as a short "how long ago" phrase.
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return pluralize(int(d/time.Minute), "minute") + " ago"
+	case d < 48*time.Hour:
+		return pluralize(int(d/time.Hour), "hour") + " ago"
+	default:
+		return pluralize(int(d/(24*time.Hour)), "day") + " ago"
+	}
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// formatTimeDifference renders a difference in minutes as "+1h", "-3h30m" or
+// "same time".
+func formatTimeDifference(minutes int) string {
+	if minutes == 0 {
+		return "same time"
+	}
+	sign := "+"
+	if minutes < 0 {
+		sign = "-"
+		minutes = -minutes
+	}
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%s%dh", sign, minutes/60)
+	}
+	return fmt.Sprintf("%s%dh%02dm", sign, minutes/60, minutes%60)
+}
@@ -0,0 +1,256 @@
+package src
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // zones for time.LoadLocation when the system has no tzdata
+)
+
+// Tables of the system tzdata that list the zones of each country, in order
+// of preference. zone.tab has one row per zone and country; zone1970.tab
+// shares zones between countries.
+var zoneTables = []string{"zone.tab", "zone1970.tab"}
+
+// A copy of zone.tab, used when the system has no tzdata. Update it together
+// with the Go version, whose time/tzdata provides the zones it names.
+//
+//go:embed tzdata/zone.tab
+var embeddedZoneTable string
+
+// Sources of a country's representative zone, most precise first
+const (
+	zoneFromCapital     = "capital"      // the zone polygon containing the capital
+	zoneFromNearestCity = "nearest_city" // the zone whose reference city is nearest the capital
+	zoneFromOffset      = "offset"       // the first zone matching the first listed UTC offset
+	zoneFromFixedOffset = "fixed_offset" // no IANA zone, the first listed UTC offset
+)
+
+// zoneEntry is a row of the zone table: a zone and the coordinates of its
+// reference city.
+type zoneEntry struct {
+	name     string
+	lat, lng float64
+}
+
+// timeZoneData is what buildCountries needs to place countries in IANA
+// zones. polygons is empty when the boundary files are missing.
+type timeZoneData struct {
+	table    map[string][]zoneEntry
+	polygons []tzPolygon
+}
+
+// loadZoneTable reads the country table of the tzdata in dir and returns the
+// IANA zones of each country keyed by cca2, in table order. Without a table
+// in dir it falls back to the embedded copy.
+func loadZoneTable(dir string) map[string][]zoneEntry {
+	var errs []error
+	for _, name := range zoneTables {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return parseZoneTable(string(content))
+	}
+	log.Printf("Warning: Could not load the IANA zone table from %s, using the embedded copy: %v", dir, errors.Join(errs...))
+	return parseZoneTable(embeddedZoneTable)
+}
+
+func parseZoneTable(content string) map[string][]zoneEntry {
+	zones := make(map[string][]zoneEntry)
+	for _, line := range strings.Split(content, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			continue
+		}
+		entry := zoneEntry{name: fields[2]}
+		entry.lat, entry.lng, _ = parseISO6709(fields[1])
+		for _, code := range strings.Split(fields[0], ",") {
+			zones[code] = append(zones[code], entry)
+		}
+	}
+	return zones
+}
+
+// parseISO6709 reads the coordinates of the zone table, ±DDMM±DDDMM or
+// ±DDMMSS±DDDMMSS.
+func parseISO6709(s string) (lat, lng float64, ok bool) {
+	split := strings.IndexAny(s[min(1, len(s)):], "+-") + 1
+	if split <= 0 {
+		return 0, 0, false
+	}
+	lat, latOK := parseDMS(s[:split], 2)
+	lng, lngOK := parseDMS(s[split:], 3)
+	return lat, lng, latOK && lngOK
+}
+
+func parseDMS(s string, degreeDigits int) (float64, bool) {
+	if len(s) != 1+degreeDigits+2 && len(s) != 1+degreeDigits+4 {
+		return 0, false
+	}
+	value := 0.0
+	for i, part := range []string{s[1 : 1+degreeDigits], s[1+degreeDigits : 3+degreeDigits], s[3+degreeDigits:]} {
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, false
+		}
+		value += float64(n) / math.Pow(60, float64(i))
+	}
+	if s[0] == '-' {
+		value = -value
+	}
+	return value, true
+}
+
+var locations sync.Map
+
+// loadLocation is time.LoadLocation with a cache, since every page computes
+// the local time of many countries.
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// representativeZone returns the zones of a country that the system tzdata
+// can load and the one used for its local time, with how it was chosen (see
+// the zoneFrom constants). capital is [lat, lng] and offsets are the UTC
+// offsets of the source in their original order.
+func representativeZone(cca2 string, capital []float64, offsets []UTCOffset, tz timeZoneData) ([]string, string, string) {
+	var entries []zoneEntry
+	var zones []string
+	for _, entry := range tz.table[cca2] {
+		if _, err := loadLocation(entry.name); err != nil {
+			log.Printf("Warning: Unknown time zone %s for %s: %v", entry.name, cca2, err)
+			continue
+		}
+		entries = append(entries, entry)
+		zones = append(zones, entry.name)
+	}
+
+	if len(capital) == 2 {
+		// Only the country's own zones, so a capital near a border or a zone
+		// missing from the table cannot pick a neighbour's zone
+		if zone := zoneAt(tz.polygons, capital[0], capital[1], zones); zone != "" {
+			return zones, zone, zoneFromCapital
+		}
+		if len(entries) > 0 {
+			nearest := entries[0]
+			for _, entry := range entries[1:] {
+				if distanceKm(capital[0], capital[1], entry.lat, entry.lng) < distanceKm(capital[0], capital[1], nearest.lat, nearest.lng) {
+					nearest = entry
+				}
+			}
+			return zones, nearest.name, zoneFromNearestCity
+		}
+	}
+
+	if len(zones) == 0 {
+		return nil, "", zoneFromFixedOffset
+	}
+	if len(offsets) > 0 {
+		for _, zone := range zones {
+			winter, summer := zoneOffsets(zone, now().Year())
+			if winter == offsets[0] || summer == offsets[0] {
+				return zones, zone, zoneFromOffset
+			}
+		}
+	}
+	return zones, zones[0], zoneFromOffset
+}
+
+// zoneOffsets returns the offsets of a zone on the 1st of January and July
+// of the given year, i.e. its standard and daylight saving offsets in either
+// order.
+func zoneOffsets(zone string, year int) (UTCOffset, UTCOffset) {
+	loc, err := loadLocation(zone)
+	if err != nil {
+		return 0, 0
+	}
+	return offsetAt(time.Date(year, time.January, 1, 12, 0, 0, 0, loc)),
+		offsetAt(time.Date(year, time.July, 1, 12, 0, 0, 0, loc))
+}
+
+// mainOffset returns the UTC offset that stands for a country in offset
+// filters and time differences: the standard offset of its representative
+// zone, as spelled in the source list when it is there, or else the first
+// listed offset.
+func mainOffset(zone string, offsets []UTCOffset) UTCOffset {
+	if zone == "" {
+		if len(offsets) > 0 {
+			return offsets[0]
+		}
+		return 0
+	}
+	standard, daylight := zoneOffsets(zone, now().Year())
+	if daylight < standard {
+		standard, daylight = daylight, standard
+	}
+	if !slices.Contains(offsets, standard) && slices.Contains(offsets, daylight) {
+		return daylight
+	}
+	return standard
+}
+
+// countryLocation returns the location of a country's local time: its IANA
+// zone, or a fixed zone for its UTC offset when it has none.
+func countryLocation(country Country) *time.Location {
+	if country.IANAZone != "" {
+		if loc, err := loadLocation(country.IANAZone); err == nil {
+			return loc
+		}
+	}
+	return country.TimeZone.Location()
+}
+
+// place is a country, IANA zone or UTC offset named in a request. cca2 is
+// empty unless it is a country.
+type place struct {
+	name, zone, cca2 string
+	loc              *time.Location
+}
+
+// resolvePlace looks key up as a country (cca3, cca2 or name, placed in the
+// zone of its capital), then as an IANA zone, then as a UTC offset.
+func resolvePlace(countries []Country, key string) (place, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return place{}, errors.New("missing country or time zone")
+	}
+	if country, ok := findCountry(countries, key); ok {
+		p := place{name: country.Name, zone: country.IANAZone, cca2: country.Cca2, loc: countryLocation(country)}
+		if p.zone == "" {
+			p.zone = country.TimeZone.String()
+		}
+		return p, nil
+	}
+	if loc, err := loadLocation(key); err == nil && !strings.EqualFold(key, "local") {
+		return place{name: key, zone: loc.String(), loc: loc}, nil
+	}
+	if offset, err := ParseUTCOffset(key); err == nil {
+		return place{name: offset.String(), zone: offset.String(), loc: offset.Location()}, nil
+	}
+	return place{}, fmt.Errorf("unknown country or time zone: %s", key)
+}
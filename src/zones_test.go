@@ -0,0 +1,55 @@
+package src
+
+import "testing"
+
+func TestLoadZoneTableFallsBackToEmbeddedCopy(t *testing.T) {
+	table := loadZoneTable(t.TempDir())
+	tests := []struct {
+		cca2 string
+		want string
+	}{
+		{"CH", "Europe/Zurich"},
+		{"IN", "Asia/Kolkata"},
+		{"US", "America/New_York"},
+	}
+	for _, tt := range tests {
+		if len(table[tt.cca2]) == 0 || table[tt.cca2][0].name != tt.want {
+			t.Errorf("first zone of %s = %v, want %s", tt.cca2, table[tt.cca2], tt.want)
+		}
+	}
+	for cca2, entries := range table {
+		for _, entry := range entries {
+			if _, err := loadLocation(entry.name); err != nil {
+				t.Errorf("%s: %v", cca2, err)
+			}
+		}
+	}
+}
+
+func TestRepresentativeZoneIgnoresOtherCountriesPolygons(t *testing.T) {
+	square := func(tzid string, lng, lat float64) tzPolygon {
+		return tzPolygon{
+			tzid:   tzid,
+			rings:  [][][2]float64{{{lng - 1, lat - 1}, {lng + 1, lat - 1}, {lng + 1, lat + 1}, {lng - 1, lat + 1}, {lng - 1, lat - 1}}},
+			minLng: lng - 1, minLat: lat - 1, maxLng: lng + 1, maxLat: lat + 1,
+		}
+	}
+	tz := timeZoneData{
+		table: map[string][]zoneEntry{
+			"CH": {{name: "Europe/Zurich", lat: 47.38, lng: 8.53}},
+		},
+		// A neighbour's polygon listed first overlaps the capital
+		polygons: []tzPolygon{square("Europe/Berlin", 7.45, 46.95), square("Europe/Zurich", 7.45, 46.95)},
+	}
+
+	_, zone, source := representativeZone("CH", []float64{46.95, 7.45}, []UTCOffset{60}, tz)
+	if zone != "Europe/Zurich" || source != zoneFromCapital {
+		t.Errorf("got %s (%s), want Europe/Zurich (capital)", zone, source)
+	}
+
+	tz.polygons = tz.polygons[:1]
+	_, zone, source = representativeZone("CH", []float64{46.95, 7.45}, []UTCOffset{60}, tz)
+	if zone != "Europe/Zurich" || source != zoneFromNearestCity {
+		t.Errorf("got %s (%s), want Europe/Zurich (nearest_city)", zone, source)
+	}
+}